@@ -0,0 +1,148 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// APETag is an APEv2 tag.
+type APETag struct {
+	// Version is 2000 for APEv2 and 1000 for APEv1.
+	Version uint32
+
+	// Offset is the position of the tag, including any
+	// header, relative to the start of the reader.
+	Offset int64
+
+	// Size is the total size of the tag, including any
+	// header and the footer.
+	Size int64
+
+	Items []*APEItem
+}
+
+// APEItemType is the type of the value of an APEItem.
+type APEItemType uint32
+
+// These are the item types from the APEv2 specification.
+const (
+	APEItemText APEItemType = iota
+	APEItemBinary
+	APEItemLocator
+)
+
+// APEItem is a single key/value item of an APEv2 tag.
+type APEItem struct {
+	Key   string
+	Flags uint32
+	Value []byte
+}
+
+// Type returns the type of the item value.
+func (i *APEItem) Type() APEItemType {
+	return APEItemType(i.Flags>>1) & 3
+}
+
+// ReadOnly reports whether the item is marked as read
+// only.
+func (i *APEItem) ReadOnly() bool {
+	return i.Flags&1 != 0
+}
+
+// Text returns the item value as a string. Text items
+// may contain several values separated by zero bytes.
+func (i *APEItem) Text() (string, error) {
+	if i.Type() == APEItemBinary {
+		return "", errors.New("id3: APEv2 item is binary")
+	}
+
+	return string(i.Value), nil
+}
+
+// Lookup returns the first item with the given key, or
+// nil. Keys are compared case-insensitively.
+func (t *APETag) Lookup(key string) *APEItem {
+	for _, item := range t.Items {
+		if strings.EqualFold(item.Key, key) {
+			return item
+		}
+	}
+
+	return nil
+}
+
+var errInvalidAPE = errors.New("id3: invalid APEv2 tag")
+
+// ReadAPE reads the APEv2 tag found at the end of r by
+// FindTrailers, where size is the length of r. It
+// returns nil if r does not have an APEv2 tag.
+func ReadAPE(r io.ReaderAt, size int64) (*APETag, error) {
+	trailers, err := FindTrailers(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range trailers {
+		if t.Kind == TrailerAPE {
+			return readAPE(r, t)
+		}
+	}
+
+	return nil, nil
+}
+
+func readAPE(r io.ReaderAt, t Trailer) (*APETag, error) {
+	data := make([]byte, t.Size)
+	if _, err := r.ReadAt(data, t.Offset); err != nil {
+		return nil, err
+	}
+
+	footer := data[len(data)-apeFooterSize:]
+	_, flags, _ := parseAPEFooter(footer)
+
+	items := data[:len(data)-apeFooterSize]
+	if flags&apeFlagHeader != 0 {
+		items = items[apeFooterSize:]
+	}
+
+	tag := &APETag{
+		Version: binary.LittleEndian.Uint32(footer[8:]),
+		Offset:  t.Offset,
+		Size:    t.Size,
+	}
+
+	count := binary.LittleEndian.Uint32(footer[16:])
+	for ; count > 0; count-- {
+		// Each item is a four byte value size and four byte
+		// item flags, followed by the zero terminated key
+		// and the value.
+		if len(items) < 8 {
+			return nil, errInvalidAPE
+		}
+
+		size := binary.LittleEndian.Uint32(items)
+		itemFlags := binary.LittleEndian.Uint32(items[4:])
+		items = items[8:]
+
+		i := bytes.IndexByte(items, 0x00)
+		if i == -1 || uint64(len(items)-i-1) < uint64(size) {
+			return nil, errInvalidAPE
+		}
+
+		tag.Items = append(tag.Items, &APEItem{
+			Key:   string(items[:i]),
+			Flags: itemFlags,
+			Value: items[i+1 : i+1+int(size)],
+		})
+		items = items[i+1+int(size):]
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// decode removes the data that frame flags add before
+// the frame data, storing it in the frame, and reverses
+// any compression. Encrypted frames are left compressed
+// until they have been decrypted. If max is non-zero,
+// frames that decompress to more than max bytes are not
+// decompressed.
+func (f *Frame) decode(max int64) error {
+	switch f.Version {
+	case Version24:
+		// Quoting from §4.1.2 of id3v2.4.0-structure.txt:
+		//   Some flags indicates that the frame header is
+		//   extended with additional information. This
+		//   information will be added to the frame header in
+		//   the same order as the flags indicating the
+		//   additions.
+		if f.Flags&FrameFlagV24GroupingIdentity != 0 {
+			if len(f.Data) < 1 {
+				return ErrInvalidFrameData
+			}
+
+			f.GroupID = f.Data[0]
+			f.Data = f.Data[1:]
+		}
+
+		if f.Flags&FrameFlagV24Encryption != 0 {
+			if len(f.Data) < 1 {
+				return ErrInvalidFrameData
+			}
+
+			f.EncryptionMethod = f.Data[0]
+			f.Data = f.Data[1:]
+		}
+
+		if f.Flags&FrameFlagV24DataLengthIndicator != 0 {
+			if len(f.Data) < 4 {
+				return ErrInvalidFrameData
+			}
+
+			f.DataLength = syncsafe(f.Data)
+			if f.DataLength == syncsafeInvalid {
+				return ErrInvalidDataLength
+			}
+
+			f.Data = f.Data[4:]
+		}
+	case Version23:
+		// Quoting from §3.3.1 of id3v2.3.0.txt:
+		//   If this flag is set, four bytes of 'decompressed
+		//   size' are appended to the frame header.
+		if f.Flags&FrameFlagV23Compression != 0 {
+			if len(f.Data) < 4 {
+				return ErrInvalidFrameData
+			}
+
+			f.DataLength = binary.BigEndian.Uint32(f.Data)
+			f.Data = f.Data[4:]
+		}
+
+		if f.Flags&FrameFlagV23Encryption != 0 {
+			if len(f.Data) < 1 {
+				return ErrInvalidFrameData
+			}
+
+			f.EncryptionMethod = f.Data[0]
+			f.Data = f.Data[1:]
+		}
+
+		if f.Flags&FrameFlagV23GroupingIdentity != 0 {
+			if len(f.Data) < 1 {
+				return ErrInvalidFrameData
+			}
+
+			f.GroupID = f.Data[0]
+			f.Data = f.Data[1:]
+		}
+	}
+
+	return f.decompress(max)
+}
+
+// decompress inflates the frame data if the frame is
+// compressed and not encrypted. It returns
+// ErrDecompressedSizeLimit if max is non-zero and the
+// frame would decompress to more than max bytes.
+func (f *Frame) decompress(max int64) error {
+	var compression, encryption FrameFlags
+	switch f.Version {
+	case Version24:
+		// A data length indicator is required for compressed
+		// frames, otherwise the frame is left compressed.
+		if f.Flags&FrameFlagV24DataLengthIndicator == 0 {
+			return nil
+		}
+
+		compression, encryption = FrameFlagV24Compression, FrameFlagV24Encryption
+	case Version23:
+		compression, encryption = FrameFlagV23Compression, FrameFlagV23Encryption
+	default:
+		return nil
+	}
+
+	if f.Flags&compression == 0 || f.Flags&encryption != 0 {
+		return nil
+	}
+
+	if max > 0 && int64(f.DataLength) > max {
+		return ErrDecompressedSizeLimit
+	}
+
+	data, err := inflate(f.Data, f.DataLength)
+	if err != nil {
+		return err
+	}
+
+	f.Data = data
+	f.Flags &^= compression
+	return nil
+}
+
+// encoded reports whether the frame data is still
+// subject to a frame-level encoding, such as compression
+// or encryption.
+func (f *Frame) encoded() bool {
+	switch f.Version {
+	case Version24:
+		return f.Flags&(FrameFlagV24Compression|FrameFlagV24Encryption|
+			FrameFlagV24Unsynchronisation) != 0
+	case Version23:
+		return f.Flags&(FrameFlagV23Compression|FrameFlagV23Encryption) != 0
+	default:
+		return f.Flags&encodingFrameFlags != 0
+	}
+}
+
+// inflate decompresses zlib compressed frame data which
+// is expected to decompress to size bytes.
+func inflate(data []byte, size uint32) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCompression, err)
+	}
+
+	data, err = ioutil.ReadAll(io.LimitReader(zr, int64(size)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCompression, err)
+	}
+
+	if uint32(len(data)) != size {
+		return nil, fmt.Errorf("%w: decompressed size mismatch", ErrInvalidCompression)
+	}
+
+	return data, nil
+}
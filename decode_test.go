@@ -0,0 +1,80 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestScanV23Compression(t *testing.T) {
+	text := textData(strings.Repeat("compressed ", 20))
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(len(text)))
+	data = append(data, compress(text)...)
+
+	tags, err := ScanTags(bytes.NewReader(rawTag(Version23, 0,
+		rawFrame("TIT2", FrameFlagV23Compression, data))))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	f := tags[0].Frames[0]
+	if f.Flags != 0 || f.DataLength != uint32(len(text)) || !bytes.Equal(f.Data, text) {
+		t.Errorf("got frame %v", f)
+	}
+
+	// The decompressed size does not match.
+	binary.BigEndian.PutUint32(data, uint32(len(text)+1))
+	if _, err := ScanTags(bytes.NewReader(rawTag(Version23, 0,
+		rawFrame("TIT2", FrameFlagV23Compression, data)))); err == nil {
+		t.Error("ScanTags succeeded with wrong decompressed size")
+	}
+
+	if _, err := ScanTags(bytes.NewReader(rawTag(Version23, 0,
+		rawFrame("TIT2", FrameFlagV23Compression, []byte("\x00\x00\x00\x05bogus"))))); err == nil {
+		t.Error("ScanTags succeeded with invalid compressed data")
+	}
+}
+
+func TestScanV24Compression(t *testing.T) {
+	text := textData(strings.Repeat("compressed ", 20))
+
+	data := make([]byte, 4)
+	putSyncsafe(data, uint32(len(text)))
+	data = append(data, compress(text)...)
+
+	var body []byte
+	body = append(body, rawFrame("TIT2", 0, nil)...)
+	putSyncsafe(body[4:], uint32(len(data)))
+	binary.BigEndian.PutUint16(body[8:],
+		uint16(FrameFlagV24Compression|FrameFlagV24DataLengthIndicator))
+	body = append(body, data...)
+
+	tags, err := ScanTags(bytes.NewReader(rawTag(Version24, 0, body)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	f := tags[0].Frames[0]
+	if f.Flags != FrameFlagV24DataLengthIndicator ||
+		f.DataLength != uint32(len(text)) || !bytes.Equal(f.Data, text) {
+		t.Errorf("got frame %v", f)
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, strings.Repeat("compressed ", 20))
+}
@@ -0,0 +1,406 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// EncodeOptions are the options used when encoding an
+// ID3v2 tag.
+type EncodeOptions struct {
+	// Version is the version of the ID3v2 tag to write. If
+	// it is zero, Version24 is used.
+	Version Version
+
+	// Padding is the number of zero bytes to write after
+	// the last frame.
+	Padding int
+
+	// Footer causes a footer to be written at the end of
+	// the tag. It is only supported by v2.4.0 and cannot be
+	// combined with padding.
+	Footer bool
+}
+
+const maxSyncsafe = 1<<28 - 1
+
+func putSyncsafe(data []byte, v uint32) {
+	_ = data[3]
+
+	data[0] = byte(v>>21) & 0x7f
+	data[1] = byte(v>>14) & 0x7f
+	data[2] = byte(v>>7) & 0x7f
+	data[3] = byte(v) & 0x7f
+}
+
+// Encode writes the frames to w as a single ID3v2 tag.
+// If opts is nil, a v2.4.0 tag without padding is
+// written.
+//
+// When writing a v2.3.0 tag, any text that is encoded
+// as UTF-8 or UTF-16BE is re-encoded as UTF-16 with a
+// BOM.
+//
+// Frames read from a v2.2.0 tag that have no v2.3.0
+// equivalent cannot be written, and cause
+// ErrInvalidFrameID to be returned.
+func (f Frames) Encode(w io.Writer, opts *EncodeOptions) error {
+	if opts == nil {
+		opts = new(EncodeOptions)
+	}
+
+	version := opts.Version
+	if version == 0 {
+		version = Version24
+	}
+
+	switch version {
+	case Version24, Version23:
+	default:
+		return errors.New("id3: unsupported version")
+	}
+
+	if opts.Padding < 0 {
+		return ErrInvalidPadding
+	}
+
+	if opts.Footer {
+		if version != Version24 {
+			return errors.New("id3: footer is only supported by v2.4.0")
+		}
+
+		if opts.Padding != 0 {
+			return ErrPaddingWithFooter
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 10))
+
+	for _, frame := range f {
+		if err := frame.encode(&buf, version); err != nil {
+			return err
+		}
+	}
+
+	buf.Write(make([]byte, opts.Padding))
+
+	size := buf.Len() - 10
+	if size > maxSyncsafe {
+		return errors.New("id3: tag too large")
+	}
+
+	var header [10]byte
+	copy(header[:], id3Token)
+	header[3] = byte(version)
+
+	if opts.Footer {
+		header[5] |= byte(TagFlagFooter)
+	}
+
+	putSyncsafe(header[6:], uint32(size))
+	copy(buf.Bytes(), header[:])
+
+	if opts.Footer {
+		// Quoting from §3.4 of id3v2.4.0-structure.txt:
+		//   The footer is a copy of the header, but with a
+		//   different identifier.
+		buf.WriteString("3DI")
+		buf.Write(header[3:])
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// frameFlagsMapping pairs equivalent v2.3.0 and v2.4.0
+// frame flags.
+var frameFlagsMapping = [...]struct{ v23, v24 FrameFlags }{
+	{FrameFlagV23TagAlterPreservation, FrameFlagV24TagAlterPreservation},
+	{FrameFlagV23FileAlterPreservation, FrameFlagV24FileAlterPreservation},
+	{FrameFlagV23ReadOnly, FrameFlagV24ReadOnly},
+	{FrameFlagV23Compression, FrameFlagV24Compression},
+	{FrameFlagV23Encryption, FrameFlagV24Encryption},
+	{FrameFlagV23GroupingIdentity, FrameFlagV24GroupingIdentity},
+}
+
+func convertFrameFlags(flags FrameFlags, from, to Version) FrameFlags {
+	if from == 0 || from == to {
+		return flags
+	}
+
+	var out FrameFlags
+	for _, m := range frameFlagsMapping {
+		switch {
+		case from == Version23 && to == Version24 && flags&m.v23 != 0:
+			out |= m.v24
+		case from == Version24 && to == Version23 && flags&m.v24 != 0:
+			out |= m.v23
+		}
+	}
+
+	if out&FrameFlagV24Compression != 0 && to == Version24 {
+		// Compressed frames require a data length indicator
+		// in v2.4.0.
+		out |= FrameFlagV24DataLengthIndicator
+	}
+
+	return out
+}
+
+func (f *Frame) encode(buf *bytes.Buffer, version Version) error {
+	var header [10]byte
+	binary.BigEndian.PutUint32(header[:], uint32(f.ID))
+
+	// v2.2.0 frame ids without a v2.3.0 equivalent are
+	// read with a trailing zero byte, and cannot be
+	// written.
+	if id := frameID(header[:]); id == 0 || id == invalidFrameID ||
+		!validIDByte(header[3]) {
+		return ErrInvalidFrameID
+	}
+
+	if err := f.Load(); err != nil {
+		return err
+	}
+
+	flags := convertFrameFlags(f.Flags, f.Version, version)
+
+	data := f.Data
+	switch version {
+	case Version24:
+		var prefix []byte
+		if flags&FrameFlagV24GroupingIdentity != 0 {
+			prefix = append(prefix, f.GroupID)
+		}
+
+		if flags&FrameFlagV24Encryption != 0 {
+			prefix = append(prefix, f.EncryptionMethod)
+		}
+
+		if flags&FrameFlagV24DataLengthIndicator != 0 {
+			length := uint32(len(data))
+			if flags&(FrameFlagV24Compression|FrameFlagV24Encryption) != 0 {
+				length = f.DataLength
+			}
+
+			var dli [4]byte
+			putSyncsafe(dli[:], length)
+			prefix = append(prefix, dli[:]...)
+		}
+
+		if prefix != nil {
+			data = append(prefix, data...)
+		}
+
+		if flags&FrameFlagV24Unsynchronisation != 0 {
+			data = unsynchronise(data)
+		}
+
+		if len(data) > maxSyncsafe {
+			return errors.New("id3: frame too large")
+		}
+
+		putSyncsafe(header[4:], uint32(len(data)))
+	case Version23:
+		if flags&(FrameFlagV23Compression|FrameFlagV23Encryption) == 0 {
+			var err error
+			if data, err = f.v23Text(); err != nil {
+				return err
+			}
+		}
+
+		var prefix []byte
+		if flags&FrameFlagV23Compression != 0 {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], f.DataLength)
+			prefix = append(prefix, size[:]...)
+		}
+
+		if flags&FrameFlagV23Encryption != 0 {
+			prefix = append(prefix, f.EncryptionMethod)
+		}
+
+		if flags&FrameFlagV23GroupingIdentity != 0 {
+			prefix = append(prefix, f.GroupID)
+		}
+
+		if prefix != nil {
+			data = append(prefix, data...)
+		}
+
+		if uint64(len(data)) > 1<<32-1 {
+			return errors.New("id3: frame too large")
+		}
+
+		binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	default:
+		panic("unhandled version")
+	}
+
+	binary.BigEndian.PutUint16(header[8:], uint16(flags))
+
+	buf.Write(header[:])
+	buf.Write(data)
+	return nil
+}
+
+// unsynchronise applies the unsynchronisation scheme
+// described in §6.1 of id3v2.4.0-structure.txt.
+func unsynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+
+	for i, v := range data {
+		out = append(out, v)
+
+		if v == 0xff && i+1 < len(data) &&
+			(data[i+1] == 0x00 || data[i+1]&0xe0 == 0xe0) {
+			out = append(out, 0x00)
+		}
+	}
+
+	return out
+}
+
+var utf16BOM = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+
+// v23Text returns the frame data with any UTF-16BE or
+// UTF-8 strings re-encoded as UTF-16 with a BOM. Neither
+// encoding is permitted by v2.3.0 of the specification.
+func (f *Frame) v23Text() ([]byte, error) {
+	data := f.Data
+	if len(data) == 0 ||
+		data[0] != textEncodingUTF16BE && data[0] != textEncodingUTF8 {
+		return data, nil
+	}
+
+	// start is the offset of the first encoded string, and
+	// count is the number of encoded strings, or -1 if they
+	// continue to the end of the frame. Any data after the
+	// last string is copied unchanged.
+	start, count := 1, -1
+	var sylt bool
+	var err error
+	switch {
+	case f.ID>>24 == 'T', f.ID == FrameIPLS:
+	case f.ID == FrameCOMM, f.ID == FrameUSLT, f.ID == FrameUSER:
+		// The language is always three bytes.
+		start = 4
+	case f.ID == FrameWXXX:
+		count = 1
+	case f.ID == FrameAPIC:
+		// The MIME type is always ISO-8859-1 and is followed
+		// by the one byte picture type.
+		start, err = latin1End(data, 1)
+		start, count = start+1, 1
+	case f.ID == FrameGEOB:
+		// The MIME type is followed by the filename and the
+		// content description.
+		start, err = latin1End(data, 1)
+		count = 2
+	case f.ID == FrameOWNE:
+		// The price paid is followed by the eight byte date
+		// of purchase.
+		start, err = latin1End(data, 1)
+		start += 8
+	case f.ID == FrameCOMR:
+		// The price is followed by the eight byte valid
+		// until date, the contact URL and the one byte
+		// received as field. The name of the seller and the
+		// description are followed by the MIME type and logo.
+		if start, err = latin1End(data, 1); err == nil {
+			start, err = latin1End(data, start+8)
+		}
+
+		start, count = start+1, 2
+	case f.ID == FrameSYLT:
+		// The language, time stamp format and content type
+		// are followed by the content descriptor, then by
+		// each string with a four byte time stamp.
+		start, sylt = 6, true
+	default:
+		return data, nil
+	}
+
+	if err != nil || len(data) < start {
+		return nil, ErrInvalidFrameData
+	}
+
+	terminator, dec := zeroByte, unicode.UTF8.NewDecoder()
+	if data[0] == textEncodingUTF16BE {
+		terminator = zeroBytes
+		dec = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	}
+
+	out := append([]byte{textEncodingUTF16}, data[1:start]...)
+	data = data[start:]
+
+	for n := 0; len(data) != 0 && n != count; n++ {
+		str, rest, terminated := data, []byte(nil), false
+		if i := indexTerminator(data, terminator); i != -1 {
+			str, rest, terminated = data[:i], data[i+len(terminator):], true
+		}
+
+		str, err := dec.Bytes(str)
+		if err != nil {
+			return nil, fmt.Errorf("id3: frame has invalid text data: %w", err)
+		}
+
+		if str, err = utf16BOM.NewEncoder().Bytes(str); err != nil {
+			return nil, fmt.Errorf("id3: frame has invalid text data: %w", err)
+		}
+
+		out = append(out, str...)
+		if terminated {
+			out = append(out, zeroBytes...)
+		}
+
+		data = rest
+		if sylt && n != 0 {
+			if len(data) < 4 {
+				return nil, ErrInvalidFrameData
+			}
+
+			out, data = append(out, data[:4]...), data[4:]
+		}
+	}
+
+	return append(out, data...), nil
+}
+
+// latin1End returns the offset that follows the
+// terminated ISO-8859-1 string at offset i of data.
+func latin1End(data []byte, i int) (int, error) {
+	if i > len(data) {
+		return 0, ErrInvalidFrameData
+	}
+
+	j := bytes.IndexByte(data[i:], 0x00)
+	if j == -1 {
+		return 0, ErrInvalidFrameData
+	}
+
+	return i + j + 1, nil
+}
+
+// indexTerminator returns the index of the first string
+// terminator in data, taking into account the alignment
+// of two byte terminators.
+func indexTerminator(data, terminator []byte) int {
+	for i := 0; i+len(terminator) <= len(data); i += len(terminator) {
+		if bytes.Equal(data[i:i+len(terminator)], terminator) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,252 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var roundTripFrames = Frames{
+	{ID: FrameTIT2, Version: Version24, Data: []byte("\x03T\xc3\xaftle")},
+	{ID: FrameTPE1, Version: Version24, Data: []byte("\x00Artist")},
+	{ID: FrameTXXX, Version: Version24, Data: []byte("\x03desc\x00value")},
+	{ID: FrameCOMM, Version: Version24, Data: []byte("\x03engdesc\x00comment")},
+	{ID: FrameAPIC, Version: Version24, Data: []byte("\x03image/png\x00\x03desc\x00\xff\x00\xff\xe0")},
+	{ID: FramePRIV, Version: Version24, Flags: FrameFlagV24GroupingIdentity, GroupID: 7,
+		Data: []byte("owner\x00\x02\x03")},
+}
+
+func TestEncodeRoundTripV24(t *testing.T) {
+	for _, opts := range []*EncodeOptions{
+		nil,
+		{Padding: 64},
+		{Footer: true},
+	} {
+		var buf bytes.Buffer
+		if err := roundTripFrames.Encode(&buf, opts); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		tags, err := ScanTags(&buf)
+		if err != nil {
+			t.Fatalf("ScanTags failed: %v", err)
+		}
+
+		if len(tags) != 1 {
+			t.Fatalf("got %d tags, want 1", len(tags))
+		}
+
+		tag := tags[0]
+		if tag.Version != Version24 {
+			t.Errorf("got version %d, want %d", tag.Version, Version24)
+		}
+
+		if opts != nil && (tag.Padding != int64(opts.Padding) || tag.HasFooter() != opts.Footer) {
+			t.Errorf("got padding %d and footer %t", tag.Padding, tag.HasFooter())
+		}
+
+		frames := tag.Frames
+		if len(frames) != len(roundTripFrames) {
+			t.Fatalf("got %d frames, want %d", len(frames), len(roundTripFrames))
+		}
+
+		for i, f := range frames {
+			want := roundTripFrames[i]
+			if f.ID != want.ID || f.Version != Version24 || f.Flags != want.Flags ||
+				f.GroupID != want.GroupID || !bytes.Equal(f.Data, want.Data) {
+				t.Errorf("frame %d: got %v, want %v", i, f, want)
+			}
+		}
+
+		checkText(t, frames, FrameTIT2, "Tïtle")
+	}
+}
+
+func TestEncodeInvalidOptions(t *testing.T) {
+	for _, opts := range []*EncodeOptions{
+		{Padding: -1},
+		{Padding: 1, Footer: true},
+		{Version: 1},
+	} {
+		if err := roundTripFrames.Encode(new(bytes.Buffer), opts); err == nil {
+			t.Errorf("Encode with %+v succeeded", *opts)
+		}
+	}
+}
+
+func TestEncodeInvalidFrameID(t *testing.T) {
+	for _, id := range []FrameID{
+		0,
+		FrameID('T'<<24 | 'I'<<16 | 'T'<<8 | 'x'),
+		FrameID('C'<<24 | 'R'<<16 | 'M'<<8), // v2.2.0 CRM
+	} {
+		frames := Frames{{ID: id, Data: []byte{0}}}
+		if err := frames.Encode(new(bytes.Buffer), nil); !errors.Is(err, ErrInvalidFrameID) {
+			t.Errorf("Encode with id %q returned %v, want %v", id, err, ErrInvalidFrameID)
+		}
+	}
+}
+
+func TestEncodeRoundTripV23(t *testing.T) {
+	var buf bytes.Buffer
+	if err := roundTripFrames.Encode(&buf, &EncodeOptions{Version: Version23}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	frames, err := Scan(&buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(frames) != len(roundTripFrames) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(roundTripFrames))
+	}
+
+	for _, f := range frames {
+		if f.Version != Version23 {
+			t.Errorf("%s: got version %d, want %d", f.ID, f.Version, Version23)
+		}
+	}
+
+	checkText(t, frames, FrameTIT2, "Tïtle")
+	checkText(t, frames, FrameTPE1, "Artist")
+
+	for i, want := range []string{
+		"\x01\xff\xfed\x00e\x00s\x00c\x00\x00\x00\xff\xfev\x00a\x00l\x00u\x00e\x00",
+		"\x01eng\xff\xfed\x00e\x00s\x00c\x00\x00\x00\xff\xfec\x00o\x00m\x00m\x00e\x00n\x00t\x00",
+		"\x01image/png\x00\x03\xff\xfed\x00e\x00s\x00c\x00\x00\x00\xff\x00\xff\xe0",
+	} {
+		if f := frames[i+2]; string(f.Data) != want {
+			t.Errorf("%s: got %q, want %q", f.ID, f.Data, want)
+		}
+	}
+
+	priv := frames[5]
+	if priv.Flags != FrameFlagV23GroupingIdentity || priv.GroupID != 7 ||
+		!bytes.Equal(priv.Data, roundTripFrames[5].Data) {
+		t.Errorf("got PRIV frame %v", priv)
+	}
+}
+
+func TestEncodeConvertFrameFlags(t *testing.T) {
+	frames := Frames{{
+		ID:      FrameTPE1,
+		Version: Version24,
+		Flags:   FrameFlagV24ReadOnly | FrameFlagV24FileAlterPreservation,
+		Data:    textData("Artist"),
+	}}
+
+	var buf bytes.Buffer
+	if err := frames.Encode(&buf, &EncodeOptions{Version: Version23}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	got, err := Scan(&buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if want := FrameFlagV23ReadOnly | FrameFlagV23FileAlterPreservation; got[0].Flags != want {
+		t.Errorf("got flags 0x%04x, want 0x%04x", got[0].Flags, want)
+	}
+}
+
+func TestEncodeDataLengthIndicator(t *testing.T) {
+	text := textData(strings.Repeat("compressed ", 20))
+
+	for _, version := range []Version{Version24, Version23} {
+		frames := Frames{
+			{ID: FrameTIT2, Version: Version24, Flags: FrameFlagV24DataLengthIndicator,
+				Data: textData("title")},
+			{ID: FrameTPE1, Version: Version24,
+				Flags:      FrameFlagV24Compression | FrameFlagV24DataLengthIndicator,
+				DataLength: uint32(len(text)), Data: compress(text)},
+		}
+
+		var buf bytes.Buffer
+		if err := frames.Encode(&buf, &EncodeOptions{Version: version}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		tags, err := ScanTags(&buf)
+		if err != nil {
+			t.Fatalf("ScanTags failed: %v", err)
+		}
+
+		got := tags[0].Frames
+		if version == Version24 && (got[0].Flags != FrameFlagV24DataLengthIndicator ||
+			got[0].DataLength != uint32(len(frames[0].Data))) {
+			t.Errorf("got TIT2 frame %v with data length %d", got[0], got[0].DataLength)
+		}
+
+		if got[1].encoded() || got[1].DataLength != uint32(len(text)) || !bytes.Equal(got[1].Data, text) {
+			t.Errorf("got TPE1 frame %v with data length %d", got[1], got[1].DataLength)
+		}
+	}
+}
+
+// utf16LE returns the ASCII string s as UTF-16 with a
+// little-endian byte order mark, as written for v2.3.0.
+func utf16LE(s string) string {
+	out := "\xff\xfe"
+	for _, c := range []byte(s) {
+		out += string([]byte{c, 0})
+	}
+
+	return out
+}
+
+func TestV23Text(t *testing.T) {
+	for _, tc := range []struct {
+		id   FrameID
+		data string
+		want string
+	}{
+		{FrameTIT2, "\x03one\x00two", "\x01" + utf16LE("one") + "\x00\x00" + utf16LE("two")},
+		{FrameIPLS, "\x03role\x00name\x00", "\x01" + utf16LE("role") + "\x00\x00" + utf16LE("name") + "\x00\x00"},
+		{FrameUSER, "\x03engterms", "\x01eng" + utf16LE("terms")},
+		{FrameWXXX, "\x03desc\x00http://example.com/", "\x01" + utf16LE("desc") + "\x00\x00http://example.com/"},
+		{FrameGEOB, "\x03text/plain\x00file\x00desc\x00\x01\x02",
+			"\x01text/plain\x00" + utf16LE("file") + "\x00\x00" + utf16LE("desc") + "\x00\x00\x01\x02"},
+		{FrameOWNE, "\x03EUR1.00\x0020170101seller",
+			"\x01EUR1.00\x0020170101" + utf16LE("seller")},
+		{FrameCOMR, "\x03EUR1.00\x0020170101http://example.com/\x00\x01seller\x00desc\x00image/png\x00logo",
+			"\x01EUR1.00\x0020170101http://example.com/\x00\x01" + utf16LE("seller") + "\x00\x00" +
+				utf16LE("desc") + "\x00\x00image/png\x00logo"},
+		{FrameSYLT, "\x03eng\x02\x01desc\x00one\x00\x00\x00\x00\x01two\x00\x00\x00\x00\x02",
+			"\x01eng\x02\x01" + utf16LE("desc") + "\x00\x00" + utf16LE("one") + "\x00\x00\x00\x00\x00\x01" +
+				utf16LE("two") + "\x00\x00\x00\x00\x00\x02"},
+		{FramePRIV, "\x03owner\x00data", "\x03owner\x00data"},
+		{FrameTIT2, "\x00latin1", "\x00latin1"},
+	} {
+		f := &Frame{ID: tc.id, Version: Version24, Data: []byte(tc.data)}
+		got, err := f.v23Text()
+		if err != nil {
+			t.Errorf("%s: v23Text failed: %v", tc.id, err)
+			continue
+		}
+
+		if string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.id, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		id   FrameID
+		data string
+	}{
+		{FrameGEOB, "\x03text/plain"},
+		{FrameCOMR, "\x03EUR1.00\x00"},
+		{FrameSYLT, "\x03eng\x02\x01desc\x00one\x00\x00\x00"},
+	} {
+		f := &Frame{ID: tc.id, Version: Version24, Data: []byte(tc.data)}
+		if _, err := f.v23Text(); err == nil {
+			t.Errorf("%s: v23Text(%q) succeeded", tc.id, tc.data)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EncryptionRegistration is an encryption method
+// registered by an ENCR frame.
+type EncryptionRegistration struct {
+	// Owner identifies the organisation responsible for
+	// the encryption method, usually with a URL or email
+	// address.
+	Owner string
+
+	// Symbol is the method symbol that frames encrypted
+	// with this method store in Frame.EncryptionMethod.
+	Symbol byte
+
+	// Data is the method specific encryption data.
+	Data []byte
+}
+
+// EncryptionRegistration interprets the frame data as an
+// encryption method registration, according to §4.25 of
+// id3v2.4.0-frames.txt.
+func (f *Frame) EncryptionRegistration() (*EncryptionRegistration, error) {
+	if f.ID != FrameENCR {
+		return nil, errors.New("id3: frame is not an ENCR frame")
+	}
+
+	if err := f.Load(); err != nil {
+		return nil, err
+	}
+
+	if f.encoded() {
+		return nil, errors.New("id3: encoding frame flags are not supported")
+	}
+
+	i := bytes.IndexByte(f.Data, 0x00)
+	if i == -1 || i+1 >= len(f.Data) {
+		return nil, ErrInvalidFrameData
+	}
+
+	owner, err := charmap.ISO8859_1.NewDecoder().Bytes(f.Data[:i])
+	if err != nil {
+		return nil, fmt.Errorf("id3: frame has invalid text data: %w", err)
+	}
+
+	return &EncryptionRegistration{
+		Owner:  string(owner),
+		Symbol: f.Data[i+1],
+		Data:   f.Data[i+2:],
+	}, nil
+}
+
+// Decrypter decrypts frames encrypted with a registered
+// encryption method.
+type Decrypter interface {
+	// Decrypt returns the decrypted frame data. If the
+	// frame was compressed before being encrypted, the
+	// returned data should still be compressed.
+	Decrypt(reg *EncryptionRegistration, data []byte) ([]byte, error)
+}
+
+// DecrypterFunc is an adapter to allow the use of an
+// ordinary function as a Decrypter.
+type DecrypterFunc func(reg *EncryptionRegistration, data []byte) ([]byte, error)
+
+// Decrypt calls fn(reg, data).
+func (fn DecrypterFunc) Decrypt(reg *EncryptionRegistration, data []byte) ([]byte, error) {
+	return fn(reg, data)
+}
+
+// decrypt decrypts the encrypted frames of a tag using
+// the encryption methods registered by ENCR frames in the
+// same tag. Frames without a matching Decrypter, or
+// whose ENCR frame is malformed, are left encrypted.
+// fail is called for frames that cannot be decrypted,
+// and decrypt stops if it returns an error.
+func (o *ScanOptions) decrypt(frames Frames, fail func(f *Frame, err error) error) error {
+	if len(o.Decrypters) == 0 {
+		return nil
+	}
+
+	var regs map[byte]*EncryptionRegistration
+	for _, f := range frames {
+		if f.ID != FrameENCR {
+			continue
+		}
+
+		reg, err := f.EncryptionRegistration()
+		if err != nil {
+			// A malformed ENCR frame only prevents frames
+			// encrypted with its method from being decrypted.
+			continue
+		}
+
+		if regs == nil {
+			regs = make(map[byte]*EncryptionRegistration)
+		}
+
+		regs[reg.Symbol] = reg
+	}
+
+	for _, f := range frames {
+		if err := o.decryptFrame(f, regs); err != nil {
+			if err := fail(f, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decryptFrame decrypts the frame if it is encrypted
+// with one of the registered methods and there is a
+// matching Decrypter.
+func (o *ScanOptions) decryptFrame(f *Frame, regs map[byte]*EncryptionRegistration) error {
+	var encryption FrameFlags
+	switch f.Version {
+	case Version24:
+		encryption = FrameFlagV24Encryption
+	case Version23:
+		encryption = FrameFlagV23Encryption
+	}
+
+	if encryption == 0 || f.Flags&encryption == 0 {
+		return nil
+	}
+
+	reg, ok := regs[f.EncryptionMethod]
+	if !ok {
+		return nil
+	}
+
+	d, ok := o.Decrypters[reg.Owner]
+	if !ok {
+		return nil
+	}
+
+	data, err := d.Decrypt(reg, f.Data)
+	if err != nil {
+		return fmt.Errorf("id3: failed to decrypt frame: %w", err)
+	}
+
+	f.Data = data
+	f.Flags &^= encryption
+	return f.decompress(o.MaxDecompressedSize)
+}
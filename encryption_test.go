@@ -0,0 +1,179 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// xorCipher is a toy cipher that XORs the frame data
+// with the registration data.
+func xorCipher(reg *EncryptionRegistration, data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, v := range data {
+		out[i] = v ^ reg.Data[i%len(reg.Data)]
+	}
+
+	return out, nil
+}
+
+func TestScanDecrypt(t *testing.T) {
+	reg := &EncryptionRegistration{Owner: "toy", Symbol: 0x80, Data: []byte{0x55, 0xaa}}
+	text := textData(strings.Repeat("secret ", 20))
+	encrypted, _ := xorCipher(reg, text)
+	compressed, _ := xorCipher(reg, compress(text))
+
+	for _, version := range []Version{Version24, Version23} {
+		frames := Frames{
+			{ID: FrameENCR, Version: Version24, Data: []byte("toy\x00\x80\x55\xaa")},
+			{ID: FrameTIT2, Version: Version24, Flags: FrameFlagV24Encryption,
+				EncryptionMethod: 0x80, Data: encrypted},
+			{ID: FrameTPE1, Version: Version24,
+				Flags: FrameFlagV24Encryption | FrameFlagV24Compression |
+					FrameFlagV24DataLengthIndicator,
+				EncryptionMethod: 0x80, DataLength: uint32(len(text)), Data: compressed},
+			{ID: FrameTALB, Version: Version24, Flags: FrameFlagV24Encryption,
+				EncryptionMethod: 0x81, Data: encrypted},
+		}
+
+		var buf bytes.Buffer
+		if err := frames.Encode(&buf, &EncodeOptions{Version: version}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		o := &ScanOptions{Decrypters: map[string]Decrypter{
+			"toy": DecrypterFunc(xorCipher),
+		}}
+		tags, err := o.ScanTags(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ScanTags failed: %v", err)
+		}
+
+		got := tags[0].Frames
+		if len(got) != 4 {
+			t.Fatalf("got %d frames, want 4", len(got))
+		}
+
+		checkText(t, got, FrameTIT2, strings.Repeat("secret ", 20))
+		checkText(t, got, FrameTPE1, strings.Repeat("secret ", 20))
+
+		// TALB is encrypted with an unregistered method and
+		// is left encrypted.
+		if talb := got[3]; !talb.encoded() || talb.EncryptionMethod != 0x81 ||
+			!bytes.Equal(talb.Data, encrypted) {
+			t.Errorf("got TALB frame %v", talb)
+		}
+
+		// Without a Decrypter every frame is left encrypted.
+		tags, err = ScanTags(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ScanTags failed: %v", err)
+		}
+
+		if tit2 := tags[0].Frames[1]; !tit2.encoded() || !bytes.Equal(tit2.Data, encrypted) {
+			t.Errorf("got TIT2 frame %v", tit2)
+		}
+	}
+}
+
+func TestEncryptionRegistration(t *testing.T) {
+	f := &Frame{ID: FrameENCR, Version: Version24, Data: []byte("toy\x00\x80\x55\xaa")}
+
+	reg, err := f.EncryptionRegistration()
+	if err != nil {
+		t.Fatalf("EncryptionRegistration failed: %v", err)
+	}
+
+	if reg.Owner != "toy" || reg.Symbol != 0x80 || !bytes.Equal(reg.Data, []byte{0x55, 0xaa}) {
+		t.Errorf("got %+v", reg)
+	}
+
+	for _, data := range []string{"toy", "toy\x00"} {
+		f.Data = []byte(data)
+		if _, err := f.EncryptionRegistration(); err == nil {
+			t.Errorf("EncryptionRegistration(%q) succeeded", data)
+		}
+	}
+}
+
+func TestScanDecryptLenient(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameENCR, Version: Version24, Data: []byte("toy\x00\x80\x55")},
+		{ID: FrameTIT2, Version: Version24, Flags: FrameFlagV24Encryption,
+			EncryptionMethod: 0x80, Data: []byte("encrypted")},
+	}, nil)
+
+	o := &ScanOptions{Decrypters: map[string]Decrypter{
+		"toy": DecrypterFunc(func(*EncryptionRegistration, []byte) ([]byte, error) {
+			return nil, errors.New("bad key")
+		}),
+	}}
+	if _, err := o.ScanTags(bytes.NewReader(data)); err == nil {
+		t.Error("ScanTags succeeded in default mode")
+	}
+
+	o.Mode = ScanLenient
+	tags, err := o.ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	// The frame that cannot be decrypted is skipped.
+	if len(tags[0].Frames) != 1 || tags[0].Frames[0].ID != FrameENCR {
+		t.Errorf("got frames %v", tags[0].Frames)
+	}
+
+	if w := tags[0].Warnings; len(w) != 1 || w[0].ID != FrameTIT2 {
+		t.Errorf("got warnings %v", w)
+	}
+}
+
+func TestScanDecryptInvalidRegistration(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameENCR, Version: Version24, Data: []byte("toy")},
+		{ID: FrameTIT2, Version: Version24, Flags: FrameFlagV24Encryption,
+			EncryptionMethod: 0x80, Data: []byte("encrypted")},
+	}, nil)
+
+	for _, mode := range []ScanMode{ScanDefault, ScanLenient} {
+		for _, lazy := range []bool{false, true} {
+			o := &ScanOptions{
+				Mode:       mode,
+				Lazy:       lazy,
+				Decrypters: map[string]Decrypter{"toy": DecrypterFunc(xorCipher)},
+			}
+			tags, err := o.ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Errorf("mode %d, lazy %t: ScanTagsAt failed: %v", mode, lazy, err)
+				continue
+			}
+
+			// Both frames are kept, and the TIT2 frame is left
+			// encrypted.
+			frames := tags[0].Frames
+			if len(frames) != 2 || frames[0].ID != FrameENCR || frames[1].ID != FrameTIT2 {
+				t.Errorf("mode %d, lazy %t: got frames %v", mode, lazy, frames)
+				continue
+			}
+
+			if frames[1].Flags&FrameFlagV24Encryption == 0 {
+				t.Errorf("mode %d, lazy %t: TIT2 frame was decrypted", mode, lazy)
+			}
+
+			wantWarnings := 0
+			if mode == ScanLenient {
+				wantWarnings = 1
+			}
+
+			if w := tags[0].Warnings; len(w) != wantWarnings ||
+				wantWarnings != 0 && (w[0].ID != FrameENCR || w[0].Err != ErrInvalidFrameData) {
+				t.Errorf("mode %d, lazy %t: got warnings %v", mode, lazy, w)
+			}
+		}
+	}
+}
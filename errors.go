@@ -0,0 +1,130 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These errors describe the ways in which a tag can be
+// malformed. When scanning, they are returned wrapped in
+// a *TagError or *FrameError, and can be tested for with
+// errors.Is.
+var (
+	ErrTruncatedTag          = errors.New("id3: tag is truncated")
+	ErrInvalidExtendedHeader = errors.New("id3: invalid extended header")
+	ErrInvalidFrameID        = errors.New("id3: invalid frame id")
+	ErrInvalidFrameSize      = errors.New("id3: invalid frame size")
+	ErrFrameTooLarge         = errors.New("id3: frame size exceeds length of tag data")
+	ErrInvalidFrameData      = errors.New("id3: frame data is invalid")
+	ErrInvalidDataLength     = errors.New("id3: invalid data length indicator")
+	ErrInvalidCompression    = errors.New("id3: invalid compressed frame")
+	ErrInvalidPadding        = errors.New("id3: invalid padding")
+	ErrPaddingWithFooter     = errors.New("id3: padding with footer")
+	ErrInvalidFooter         = errors.New("id3: invalid footer")
+
+	// These errors are returned when a tag exceeds one of
+	// the limits set in ScanOptions. They are never
+	// recorded as a Warning.
+	ErrTagSizeLimit          = errors.New("id3: tag exceeds size limit")
+	ErrFrameSizeLimit        = errors.New("id3: frame exceeds size limit")
+	ErrFrameCountLimit       = errors.New("id3: tag exceeds frame count limit")
+	ErrDecompressedSizeLimit = errors.New("id3: frame exceeds decompressed size limit")
+
+	// ErrCRCMismatch is returned in strict mode, or recorded
+	// as a Warning in lenient mode, when the CRC-32 in the
+	// extended header of a tag does not match the tag data.
+	ErrCRCMismatch = errors.New("id3: extended header CRC mismatch")
+
+	// ErrFrameSizeNotSyncsafe is recorded as a Warning in
+	// lenient mode when a v2.4.0 frame size is found to have
+	// been written as a plain 32-bit integer.
+	ErrFrameSizeNotSyncsafe = errors.New("id3: frame size is not syncsafe")
+)
+
+// TagError records a problem with a tag that is not
+// specific to one of its frames.
+type TagError struct {
+	// Offset is the position of the problem relative to
+	// the start of the reader.
+	Offset int64
+
+	// TagIndex is the index of the tag amongst the tags
+	// read.
+	TagIndex int
+
+	Err error
+}
+
+func (e *TagError) Error() string {
+	return describe(e.Err, 0, e.Offset)
+}
+
+// Unwrap returns the underlying error.
+func (e *TagError) Unwrap() error {
+	return e.Err
+}
+
+// FrameError records a problem with a frame of a tag.
+type FrameError struct {
+	// Offset is the position of the frame header relative
+	// to the start of the reader.
+	Offset int64
+
+	// TagIndex is the index of the tag amongst the tags
+	// read.
+	TagIndex int
+
+	// ID is the id of the frame, or zero if the frame id
+	// is invalid.
+	ID FrameID
+
+	Err error
+}
+
+func (e *FrameError) Error() string {
+	return describe(e.Err, e.ID, e.Offset)
+}
+
+// Unwrap returns the underlying error.
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// Warning is a recoverable problem found while scanning
+// a tag in lenient mode.
+type Warning struct {
+	// Offset is the position of the problem relative to
+	// the start of the reader.
+	Offset int64
+
+	// ID is the id of the frame with the problem, or zero
+	// if the problem is not with a frame or the frame id is
+	// invalid.
+	ID FrameID
+
+	Err error
+}
+
+func (w Warning) String() string {
+	return describe(w.Err, w.ID, w.Offset)
+}
+
+// describe formats err with the frame id, if any, and
+// the offset at which it occurred.
+func describe(err error, id FrameID, offset int64) string {
+	if id == 0 {
+		return fmt.Sprintf("%v at offset %d", err, offset)
+	}
+
+	raw := [4]byte{
+		byte(id >> 24),
+		byte(id >> 16),
+		byte(id >> 8),
+		byte(id),
+	}
+	return fmt.Sprintf("%v in %s frame at offset %d", err, raw[:], offset)
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestScanFrameError(t *testing.T) {
+	good := rawTag(Version23, 0, rawFrame("TIT2", 0, textData("title")))
+	bad := rawTag(Version23, 0, append(rawFrame("TPE1", 0, textData("artist")),
+		rawFrame("TALB", FrameFlagV23Compression, []byte("\x00\x00\x00\x05bogus"))...))
+
+	data := append([]byte("audio"), good...)
+	data = append(data, bad...)
+
+	_, err := ScanTags(bytes.NewReader(data))
+
+	var fe *FrameError
+	if !errors.As(err, &fe) {
+		t.Fatalf("got %#v, want *FrameError", err)
+	}
+
+	// The TALB frame follows the header of the second tag
+	// and the TPE1 frame.
+	offset := int64(5 + len(good) + 10 + 10 + len(textData("artist")))
+	if fe.Offset != offset || fe.TagIndex != 1 || fe.ID != FrameTALB ||
+		!errors.Is(err, ErrInvalidCompression) {
+		t.Errorf("got %+v", fe)
+	}
+
+	tags, err := ScanTagsAt(bytes.NewReader(bad), int64(len(bad)))
+	if !errors.As(err, &fe) || fe.Offset != 20+int64(len(textData("artist"))) || fe.TagIndex != 0 {
+		t.Errorf("ScanTagsAt returned %v, %v", tags, err)
+	}
+}
+
+func TestScanInvalidFrameID(t *testing.T) {
+	data := append([]byte("audio"), rawTag(Version23, 0, append(rawFrame("TIT2", 0, textData("title")),
+		"TI!2\x00\x00\x00\x01\x00\x00x"...))...)
+
+	_, err := ScanTags(bytes.NewReader(data))
+
+	var fe *FrameError
+	if !errors.As(err, &fe) || !errors.Is(err, ErrInvalidFrameID) {
+		t.Fatalf("got %#v, want *FrameError", err)
+	}
+
+	if want := int64(5 + 10 + 10 + len(textData("title"))); fe.Offset != want || fe.ID != 0 {
+		t.Errorf("got %+v, want offset %d", fe, want)
+	}
+
+}
+
+func TestScanTagError(t *testing.T) {
+	// The footer is not a copy of the header.
+	data := encodeTag(t, Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}},
+		&EncodeOptions{Footer: true})
+	data[len(data)-10] = 'X'
+
+	_, err := ScanTags(bytes.NewReader(data))
+
+	var te *TagError
+	if !errors.As(err, &te) || te.Err != ErrInvalidFooter || te.Offset != int64(len(data)-10) {
+		t.Errorf("got %#v, want *TagError", err)
+	}
+}
+
+func TestErrorStrings(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{&TagError{Offset: 10, Err: ErrInvalidFooter}, "id3: invalid footer at offset 10"},
+		{&FrameError{Offset: 20, ID: FrameTIT2, Err: ErrInvalidFrameData},
+			"id3: frame data is invalid in TIT2 frame at offset 20"},
+		{&FrameError{Offset: 30, Err: ErrInvalidFrameID}, "id3: invalid frame id at offset 30"},
+	} {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestScanTruncatedError(t *testing.T) {
+	good := rawTag(Version23, 0, rawFrame("TIT2", 0, textData("title")))
+	truncated := rawTag(Version23, 0, append(rawFrame("TPE1", 0, textData("artist")),
+		rawFrame("TALB", 0, textData("album"))...))
+	truncated = truncated[:len(truncated)-2]
+
+	data := append(append([]byte(nil), good...), truncated...)
+
+	for i, err := range scanAll(new(ScanOptions), data) {
+		if i == 1 {
+			// ScanTagsAt does not find the second tag, as it
+			// is neither prepended nor appended.
+			continue
+		}
+
+		var te *TagError
+		if !errors.As(err, &te) || te.Err != ErrTruncatedTag ||
+			te.Offset != int64(len(good)) || te.TagIndex != 1 {
+			t.Errorf("reader %d: got %#v, want truncated tag error", i, err)
+		}
+	}
+
+	_, err := ScanTagsAt(bytes.NewReader(truncated), int64(len(truncated)))
+	if !errors.Is(err, ErrTruncatedTag) {
+		t.Errorf("ScanTagsAt returned %v, want ErrTruncatedTag", err)
+	}
+}
+
+func TestScanUnsynchronisedErrorOffset(t *testing.T) {
+	// Unsynchronisation inserts a zero byte after the first
+	// 0xFF of the TIT2 frame, which must be accounted for
+	// in the offset of the invalid frame id that follows.
+	body := append(rawFrame("TIT2", 0, textData("\xff\xff")), "TI!2\x00\x00\x00\x01\x00\x00x"...)
+	data := rawTag(Version23, TagFlagUnsynchronisation, unsynchronise(body))
+
+	_, err := ScanTags(bytes.NewReader(data))
+
+	var fe *FrameError
+	if !errors.As(err, &fe) || fe.Err != ErrInvalidFrameID {
+		t.Fatalf("got %#v, want *FrameError", err)
+	}
+
+	if want := int64(bytes.Index(data, []byte("TI!2"))); fe.Offset != want {
+		t.Errorf("got offset %d, want %d", fe.Offset, want)
+	}
+}
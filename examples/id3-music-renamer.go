@@ -9,6 +9,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"os"
@@ -22,13 +23,39 @@ import (
 var dryrun = flag.Bool("dry-run", false, "does not perform the file renaming")
 
 func scan(work workUnit) error {
-	frames, err := id3v2.ScanFile(work.path)
+	f, err := os.Open(work.path)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
+
+	// Stop reading as soon as both frames have been found
+	// or the first tag has been read. Any tags that follow
+	// it are ignored.
+	var tit2, tpe1 *id3v2.Frame
+	var tag *id3v2.Tag
+	r := id3v2.NewReader(f)
+	for tit2 == nil || tpe1 == nil {
+		frame, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if tag == nil {
+			tag = r.Tag()
+		} else if r.Tag() != tag {
+			break
+		}
 
-	tit2 := frames.Lookup(id3v2.FrameTIT2)
-	tpe1 := frames.Lookup(id3v2.FrameTPE1)
+		switch frame.ID {
+		case id3v2.FrameTIT2:
+			tit2 = frame
+		case id3v2.FrameTPE1:
+			tpe1 = frame
+		}
+	}
 
 	if tit2 == nil || tpe1 == nil {
 		if filepath.Ext(work.path) != ".mp3" {
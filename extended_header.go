@@ -0,0 +1,193 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import "encoding/binary"
+
+// ExtendedHeader is the optional extended header of an
+// ID3v2 tag.
+type ExtendedHeader struct {
+	// Update is set if the tag is an update of a tag
+	// found earlier in the file. It is only defined by
+	// v2.4.0.
+	Update bool
+
+	// HasCRC is set if CRC contains a CRC-32 of the tag
+	// data.
+	HasCRC bool
+	CRC    uint32
+
+	// HasRestrictions is set if the tag was written
+	// subject to Restrictions. It is only defined by
+	// v2.4.0.
+	HasRestrictions bool
+	Restrictions    Restrictions
+
+	// PaddingSize is the size of the padding recorded in
+	// the extended header. It is only defined by v2.3.0.
+	PaddingSize uint32
+}
+
+const (
+	extendedFlagV24Update       = 0x40
+	extendedFlagV24CRC          = 0x20
+	extendedFlagV24Restrictions = 0x10
+
+	extendedFlagV23CRC = 0x8000
+)
+
+func parseExtendedHeader(data []byte, version Version) (*ExtendedHeader, error) {
+	var eh ExtendedHeader
+	switch version {
+	case Version24:
+		// Quoting from §3.2 of id3v2.4.0-structure.txt:
+		//   Extended header size   4 * %0xxxxxxx
+		//   Number of flag bytes       $01
+		//   Extended Flags             $xx
+		if len(data) < 6 || data[4] != 1 {
+			return nil, ErrInvalidExtendedHeader
+		}
+
+		flags := data[5]
+		data = data[6:]
+
+		// Each flag that is set is followed by the length of
+		// its data and the data itself, in the order of the
+		// flags.
+		for _, flag := range [...]byte{
+			extendedFlagV24Update,
+			extendedFlagV24CRC,
+			extendedFlagV24Restrictions,
+		} {
+			if flags&flag == 0 {
+				continue
+			}
+
+			if len(data) < 1 || len(data) < 1+int(data[0]) {
+				return nil, ErrInvalidExtendedHeader
+			}
+
+			flagData := data[1 : 1+data[0]]
+			data = data[1+data[0]:]
+
+			switch flag {
+			case extendedFlagV24Update:
+				if len(flagData) != 0 {
+					return nil, ErrInvalidExtendedHeader
+				}
+
+				eh.Update = true
+			case extendedFlagV24CRC:
+				// The CRC is stored as a 35 bit synchsafe
+				// integer.
+				if len(flagData) != 5 || flagData[0]&0xf0 != 0 {
+					return nil, ErrInvalidExtendedHeader
+				}
+
+				crc := syncsafe(flagData[1:])
+				if crc == syncsafeInvalid {
+					return nil, ErrInvalidExtendedHeader
+				}
+
+				eh.HasCRC = true
+				eh.CRC = uint32(flagData[0])<<28 | crc
+			case extendedFlagV24Restrictions:
+				if len(flagData) != 1 {
+					return nil, ErrInvalidExtendedHeader
+				}
+
+				eh.HasRestrictions = true
+				eh.Restrictions = Restrictions(flagData[0])
+			}
+		}
+	case Version23:
+		// Quoting from §3.2 of id3v2.3.0.txt:
+		//   Extended header size   $xx xx xx xx
+		//   Extended Flags         $xx xx
+		//   Size of padding        $xx xx xx xx
+		if len(data) < 10 {
+			return nil, ErrInvalidExtendedHeader
+		}
+
+		flags := binary.BigEndian.Uint16(data[4:])
+		eh.PaddingSize = binary.BigEndian.Uint32(data[6:])
+
+		if flags&extendedFlagV23CRC != 0 {
+			if len(data) < 14 {
+				return nil, ErrInvalidExtendedHeader
+			}
+
+			eh.HasCRC = true
+			eh.CRC = binary.BigEndian.Uint32(data[10:])
+		}
+	default:
+		panic("unhandled version")
+	}
+
+	return &eh, nil
+}
+
+// Restrictions are the tag restrictions defined in §3.2
+// of id3v2.4.0-structure.txt.
+type Restrictions byte
+
+// TagSize returns the maximum number of frames and the
+// maximum total size of the tag in bytes.
+func (r Restrictions) TagSize() (frames, size int) {
+	switch r >> 6 {
+	case 0:
+		return 128, 1 << 20
+	case 1:
+		return 64, 128 << 10
+	case 2:
+		return 32, 40 << 10
+	default:
+		return 32, 4 << 10
+	}
+}
+
+// TextEncoding reports whether text is restricted to
+// the ISO-8859-1 and UTF-8 encodings.
+func (r Restrictions) TextEncoding() bool {
+	return r&0x20 != 0
+}
+
+// TextSize returns the maximum length, in characters, of
+// any string in the tag. It returns zero if the length
+// is not restricted.
+func (r Restrictions) TextSize() int {
+	switch (r >> 3) & 0x03 {
+	case 0:
+		return 0
+	case 1:
+		return 1024
+	case 2:
+		return 128
+	default:
+		return 30
+	}
+}
+
+// ImageEncoding reports whether images are restricted
+// to the PNG and JPEG formats.
+func (r Restrictions) ImageEncoding() bool {
+	return r&0x04 != 0
+}
+
+// ImageSize returns the maximum dimensions of images in
+// pixels. It returns zero if the size is not restricted.
+// If exact is true, images must be exactly this size.
+func (r Restrictions) ImageSize() (width, height int, exact bool) {
+	switch r & 0x03 {
+	case 0:
+		return 0, 0, false
+	case 1:
+		return 256, 256, false
+	case 2:
+		return 64, 64, false
+	default:
+		return 64, 64, true
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestParseExtendedHeaderV24(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 15, // size
+		1,                   // number of flag bytes
+		0x70,                // update, CRC and restrictions
+		0,                   // update
+		5, 0x01, 0, 0, 0, 0, // CRC
+		1, 0x76, // restrictions
+	}
+	putSyncsafe(data[9:], 0x2345678)
+
+	eh, err := parseExtendedHeader(data, Version24)
+	if err != nil {
+		t.Fatalf("parseExtendedHeader failed: %v", err)
+	}
+
+	if !eh.Update || !eh.HasCRC || eh.CRC != 0x12345678 ||
+		!eh.HasRestrictions || eh.Restrictions != 0x76 {
+		t.Errorf("got %+v", eh)
+	}
+
+	for _, data := range [][]byte{
+		data[:5],
+		append(data[:4:4], 2, 0x00),
+		data[:len(data)-1],
+	} {
+		if _, err := parseExtendedHeader(data, Version24); err == nil {
+			t.Errorf("parseExtendedHeader(%x) succeeded", data)
+		}
+	}
+}
+
+func TestParseExtendedHeaderV23(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 10, // size
+		0x80, 0x00, // CRC
+		0, 0, 0x01, 0x00, // size of padding
+		0x12, 0x34, 0x56, 0x78, // CRC
+	}
+
+	eh, err := parseExtendedHeader(data, Version23)
+	if err != nil {
+		t.Fatalf("parseExtendedHeader failed: %v", err)
+	}
+
+	if eh.Update || !eh.HasCRC || eh.CRC != 0x12345678 ||
+		eh.HasRestrictions || eh.PaddingSize != 256 {
+		t.Errorf("got %+v", eh)
+	}
+
+	if _, err := parseExtendedHeader(data[:12], Version23); err == nil {
+		t.Error("parseExtendedHeader succeeded with truncated CRC")
+	}
+}
+
+func TestRestrictions(t *testing.T) {
+	for _, tc := range []struct {
+		r             Restrictions
+		frames, size  int
+		textEncoding  bool
+		textSize      int
+		imageEncoding bool
+		width, height int
+		exact         bool
+	}{
+		{0x00, 128, 1 << 20, false, 0, false, 0, 0, false},
+		{0x76, 64, 128 << 10, true, 128, true, 64, 64, false},
+		{0x89, 32, 40 << 10, false, 1024, false, 256, 256, false},
+		{0xff, 32, 4 << 10, true, 30, true, 64, 64, true},
+	} {
+		frames, size := tc.r.TagSize()
+		width, height, exact := tc.r.ImageSize()
+		if frames != tc.frames || size != tc.size ||
+			tc.r.TextEncoding() != tc.textEncoding ||
+			tc.r.TextSize() != tc.textSize ||
+			tc.r.ImageEncoding() != tc.imageEncoding ||
+			width != tc.width || height != tc.height || exact != tc.exact {
+			t.Errorf("%#02x: got %d, %d, %t, %d, %t, %d, %d, %t", tc.r,
+				frames, size, tc.r.TextEncoding(), tc.r.TextSize(),
+				tc.r.ImageEncoding(), width, height, exact)
+		}
+	}
+}
+
+func TestScanTagsExtendedHeader(t *testing.T) {
+	body := []byte{0, 0, 0, 8, 1, 0x10, 1, 0x76}
+	body = append(body, encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, nil)[10:]...)
+
+	tags, err := ScanTags(bytes.NewReader(rawTag(Version24, TagFlagExtendedHeader, body)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if eh := tags[0].ExtendedHeader; eh == nil || eh.HasCRC || eh.Restrictions != 0x76 {
+		t.Errorf("got extended header %+v", eh)
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, "title")
+}
+
+func TestScanV23CRC(t *testing.T) {
+	frames := rawFrame("TIT2", 0, textData("title"))
+
+	for _, valid := range []bool{true, false} {
+		crc := crc32.ChecksumIEEE(frames)
+		if !valid {
+			crc++
+		}
+
+		// The CRC of a v2.3.0 tag does not cover the padding.
+		eh := []byte{0, 0, 0, 10, 0x80, 0, 0, 0, 0, 8, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(eh[10:], crc)
+		body := append(append(eh, frames...), make([]byte, 8)...)
+		data := rawTag(Version23, TagFlagExtendedHeader, body)
+
+		tags, err := ScanTags(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ScanTags failed: %v", err)
+		}
+
+		if !tags[0].ExtendedHeader.HasCRC || tags[0].ExtendedHeader.CRC != crc {
+			t.Errorf("got extended header %+v", tags[0].ExtendedHeader)
+		}
+
+		if tags[0].CRCMismatch == valid {
+			t.Errorf("got CRCMismatch %t for valid CRC %t", tags[0].CRCMismatch, valid)
+		}
+
+		o := &ScanOptions{Mode: ScanStrict}
+		if _, err := o.ScanTags(bytes.NewReader(data)); valid && err != nil {
+			t.Errorf("ScanTags failed in strict mode: %v", err)
+		} else if !valid && !errors.Is(err, ErrCRCMismatch) {
+			t.Errorf("got %v in strict mode, want ErrCRCMismatch", err)
+		}
+	}
+}
+
+func TestScanV24CRC(t *testing.T) {
+	body := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, &EncodeOptions{Padding: 8})[10:]
+
+	eh := []byte{0, 0, 0, 12, 1, 0x20, 5, 0, 0, 0, 0, 0}
+	crc := crc32.ChecksumIEEE(body)
+	eh[7] = byte(crc >> 28)
+	putSyncsafe(eh[8:], crc&0x0fffffff)
+
+	tags, err := ScanTags(bytes.NewReader(rawTag(Version24, TagFlagExtendedHeader, append(eh, body...))))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if tags[0].ExtendedHeader.CRC != crc || tags[0].CRCMismatch {
+		t.Errorf("got extended header %+v and CRCMismatch %t",
+			tags[0].ExtendedHeader, tags[0].CRCMismatch)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// rewritePadding is the amount of padding reserved when
+// a file has to be rewritten to make room for a tag.
+const rewritePadding = 1 << 10
+
+// UpdateOptions are the options used when updating the
+// tags of a file.
+type UpdateOptions struct {
+	// PreserveModTime causes the modification time of the
+	// file to be restored after it has been updated.
+	PreserveModTime bool
+}
+
+// UpdateFile replaces the ID3v2 tag at the beginning of
+// the file with a tag containing frames, or inserts one
+// if the file does not begin with a tag.
+//
+// If the new tag fits within the space occupied by the
+// existing tag, including its padding, the tag is
+// overwritten in place. Otherwise the file is rewritten
+// with room for the tag to grow. The rewritten file is
+// written to a temporary file in the same directory
+// which then replaces the original, so the original is
+// left intact if the rewrite fails.
+//
+// The new tag has the same version as the existing tag,
+// or Version24 if there was none or it was a v2.2.0 tag.
+//
+// UpdateFile uses the default options. It is equivalent
+// to calling UpdateFile on a zero UpdateOptions.
+func UpdateFile(path string, frames Frames) error {
+	return new(UpdateOptions).UpdateFile(path, frames)
+}
+
+// UpdateFile is like the package level UpdateFile but
+// uses the options in o.
+func (o *UpdateOptions) UpdateFile(path string, frames Frames) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	oldSize, version, err := leadingTag(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	if version != Version23 {
+		// v2.2.0 tags cannot be written and are upgraded.
+		version = Version24
+	}
+
+	var buf bytes.Buffer
+	if err := frames.Encode(&buf, &EncodeOptions{Version: version}); err != nil {
+		return err
+	}
+
+	padding := oldSize - int64(buf.Len())
+	if padding < 0 {
+		padding = rewritePadding
+	}
+
+	tag := buf.Bytes()
+	if padding != 0 {
+		buf.Reset()
+		if err := frames.Encode(&buf, &EncodeOptions{
+			Version: version,
+			Padding: int(padding),
+		}); err != nil {
+			return err
+		}
+
+		tag = buf.Bytes()
+	}
+
+	if int64(len(tag)) != oldSize {
+		return o.rewriteFile(path, fi, func(w io.Writer) error {
+			if _, err := w.Write(tag); err != nil {
+				return err
+			}
+
+			_, err := io.Copy(w, io.NewSectionReader(f, oldSize, fi.Size()-oldSize))
+			return err
+		})
+	}
+
+	if _, err := f.WriteAt(tag, 0); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return o.restoreModTime(path, fi)
+}
+
+func (o *UpdateOptions) restoreModTime(path string, fi os.FileInfo) error {
+	if !o.PreserveModTime {
+		return nil
+	}
+
+	return os.Chtimes(path, time.Now(), fi.ModTime())
+}
+
+// rewriteFile replaces the file at path, described by
+// fi, with the contents written by write. The contents
+// are written to a temporary file which is synced to
+// disk before being renamed over the original file.
+func (o *UpdateOptions) rewriteFile(path string, fi os.FileInfo, write func(io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := o.restoreModTime(tmp.Name(), fi); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename is durable. Not all
+	// platforms support this, so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// leadingTag returns the size and version of the tag at
+// the beginning of r. It returns a size and version of
+// zero if r does not begin with a tag.
+func leadingTag(r io.ReaderAt, size int64) (int64, Version, error) {
+	var header [10]byte
+	if _, err := r.ReadAt(header[:], 0); err == io.EOF {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	n := tagSize(header[:])
+	if n == -1 {
+		return 0, 0, nil
+	}
+
+	if int64(n) > size {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	return int64(n), Version(header[3]), nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTempFile(t *testing.T, data []byte) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "id3v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "test.mp3")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func encodeTag(t *testing.T, frames Frames, opts *EncodeOptions) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := frames.Encode(&buf, opts); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestUpdateFile(t *testing.T) {
+	audio := []byte("audio data")
+
+	for _, tc := range []struct {
+		name    string
+		title   string
+		old     []byte
+		version Version
+	}{
+		{"in place", "new", encodeTag(t, Frames{
+			{ID: FrameTIT2, Version: Version24, Data: textData("old")},
+		}, &EncodeOptions{Padding: 32}), Version24},
+		{"grow", string(bytes.Repeat([]byte("long title "), 100)), encodeTag(t, Frames{
+			{ID: FrameTIT2, Version: Version24, Data: textData("old")},
+		}, &EncodeOptions{Padding: 32}), Version24},
+		{"v2.3", "new", encodeTag(t, Frames{
+			{ID: FrameTIT2, Version: Version24, Data: textData("old")},
+		}, &EncodeOptions{Version: Version23, Padding: 32}), Version23},
+		{"insert", "new", nil, Version24},
+	} {
+		path, cleanup := writeTempFile(t, append(tc.old, audio...))
+		defer cleanup()
+
+		if err := UpdateFile(path, Frames{
+			{ID: FrameTIT2, Version: Version24, Data: textData(tc.title)},
+		}); err != nil {
+			t.Fatalf("%s: UpdateFile failed: %v", tc.name, err)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		size, version, err := leadingTag(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: leadingTag failed: %v", tc.name, err)
+		}
+
+		if version != tc.version {
+			t.Errorf("%s: got version %d, want %d", tc.name, version, tc.version)
+		}
+
+		frames, err := Scan(bytes.NewReader(data[:size]))
+		if err != nil {
+			t.Fatalf("%s: Scan failed: %v", tc.name, err)
+		}
+
+		checkText(t, frames, FrameTIT2, tc.title)
+
+		if tc.name == "in place" && len(data) != len(tc.old)+len(audio) {
+			t.Errorf("%s: file size changed from %d to %d", tc.name, len(tc.old)+len(audio), len(data))
+		}
+
+		if !bytes.Equal(data[size:], audio) {
+			t.Errorf("%s: audio was modified", tc.name)
+		}
+	}
+}
+
+func TestUpdateFileRewrite(t *testing.T) {
+	path, cleanup := writeTempFile(t, []byte("audio data"))
+	defer cleanup()
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(filepath.Dir(path), "link.mp3")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&UpdateOptions{PreserveModTime: true}).UpdateFile(link, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}); err != nil {
+		t.Fatalf("UpdateFile failed: %v", err)
+	}
+
+	if fi, err := os.Lstat(link); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("got permissions %v, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("got modification time %v, want %v", fi.ModTime(), modTime)
+	}
+
+	// The temporary file must have been renamed over the
+	// original rather than left behind.
+	names, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 2 {
+		t.Errorf("got %d files in directory, want 2", len(names))
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, _, err := leadingTag(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("leadingTag failed: %v", err)
+	}
+
+	if size == 0 || string(data[size:]) != "audio data" {
+		t.Errorf("got %q", data)
+	}
+}
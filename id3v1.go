@@ -0,0 +1,209 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	v1Size         = 128
+	v1ExtendedSize = 227
+)
+
+// V1GenreNone is the genre index of a V1Tag that does not
+// have a genre.
+const V1GenreNone = 0xff
+
+// V1Tag is an ID3v1 or ID3v1.1 tag, optionally with an
+// enhanced "TAG+" block, found at the end of a file.
+type V1Tag struct {
+	// Title, Artist and Album include the continuation
+	// stored in the enhanced block, if present.
+	Title   string
+	Artist  string
+	Album   string
+	Year    string
+	Comment string
+
+	// Track is the track number of an ID3v1.1 tag. It is
+	// zero for ID3v1 tags.
+	Track byte
+
+	// Genre is the index of the genre in the ID3v1 genre
+	// list, or V1GenreNone.
+	Genre byte
+
+	// Extended is set if the tag is preceded by an
+	// enhanced "TAG+" block. The remaining fields are only
+	// defined by the enhanced block.
+	Extended bool
+
+	// Speed is 0 if unset, or 1 to 4 for slow, medium,
+	// fast and hardcore.
+	Speed byte
+
+	// FreeGenre is the free-text genre.
+	FreeGenre string
+
+	// StartTime and EndTime are the start and end of the
+	// music in the form mmm:ss.
+	StartTime string
+	EndTime   string
+
+	// Offset is the position of the tag, including any
+	// enhanced block, relative to the start of the reader.
+	Offset int64
+
+	// Size is the total size of the tag, including any
+	// enhanced block.
+	Size int64
+}
+
+// ReadV1 reads the ID3v1 tag at the end of r, where size
+// is the length of r. It returns nil if r does not end
+// with an ID3v1 tag.
+func ReadV1(r io.ReaderAt, size int64) (*V1Tag, error) {
+	if size < v1Size {
+		return nil, nil
+	}
+
+	var data [v1Size]byte
+	if _, err := r.ReadAt(data[:], size-v1Size); err != nil {
+		return nil, err
+	}
+
+	if string(data[:3]) != "TAG" {
+		return nil, nil
+	}
+
+	tag := &V1Tag{
+		Title:   v1String(data[3:33]),
+		Artist:  v1String(data[33:63]),
+		Album:   v1String(data[63:93]),
+		Year:    v1String(data[93:97]),
+		Comment: v1String(data[97:127]),
+		Genre:   data[127],
+		Offset:  size - v1Size,
+		Size:    v1Size,
+	}
+
+	if data[125] == 0 && data[126] != 0 {
+		// ID3v1.1 stores the track number in the last byte
+		// of the comment, preceded by a zero byte.
+		tag.Comment = v1String(data[97:125])
+		tag.Track = data[126]
+	}
+
+	if size < v1Size+v1ExtendedSize {
+		return tag, nil
+	}
+
+	var ext [v1ExtendedSize]byte
+	if _, err := r.ReadAt(ext[:], size-v1Size-v1ExtendedSize); err != nil {
+		return nil, err
+	}
+
+	if string(ext[:4]) != "TAG+" {
+		return tag, nil
+	}
+
+	// The title, artist and album of the enhanced block
+	// follow on from the first 30 bytes stored in the
+	// ID3v1 tag.
+	tag.Title = v1String(append(data[3:33:33], ext[4:64]...))
+	tag.Artist = v1String(append(data[33:63:63], ext[64:124]...))
+	tag.Album = v1String(append(data[63:93:93], ext[124:184]...))
+	tag.Speed = ext[184]
+	tag.FreeGenre = v1String(ext[185:215])
+	tag.StartTime = v1String(ext[215:221])
+	tag.EndTime = v1String(ext[221:227])
+
+	tag.Extended = true
+	tag.Offset -= v1ExtendedSize
+	tag.Size += v1ExtendedSize
+	return tag, nil
+}
+
+// v1String decodes a zero padded ISO-8859-1 string.
+func v1String(data []byte) string {
+	if i := bytes.IndexByte(data, 0x00); i != -1 {
+		data = data[:i]
+	}
+
+	data = bytes.TrimRight(data, " ")
+
+	str, err := charmap.ISO8859_1.NewDecoder().Bytes(data)
+	if err != nil {
+		panic("id3: ISO-8859-1 decoding failed")
+	}
+
+	return string(str)
+}
+
+// GenreName returns the name of the genre, preferring
+// the free-text genre of the enhanced block. It returns
+// an empty string if the genre is unknown.
+func (t *V1Tag) GenreName() string {
+	if t.FreeGenre != "" {
+		return t.FreeGenre
+	}
+
+	if int(t.Genre) < len(v1Genres) {
+		return v1Genres[t.Genre]
+	}
+
+	return ""
+}
+
+// Frames returns v2.3.0 frames equivalent to the tag.
+// Fields that are empty are omitted.
+func (t *V1Tag) Frames() Frames {
+	var frames Frames
+	add := func(id FrameID, text string) {
+		if text == "" {
+			return
+		}
+
+		enc, terminator := byte(textEncodingISO88591), zeroByte
+		data, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			// The fields may have been changed to include
+			// characters ISO-8859-1 cannot represent.
+			enc, terminator = textEncodingUTF16, zeroBytes
+			data, _ = utf16BOM.NewEncoder().Bytes([]byte(text))
+		}
+
+		prefix := []byte{enc}
+		if id == FrameCOMM {
+			// The language of the comment is unknown, and the
+			// comment has no content descriptor.
+			prefix = append(append(prefix, "XXX"...), terminator...)
+		}
+
+		frames = append(frames, &Frame{
+			ID:      id,
+			Version: Version23,
+			Data:    append(prefix, data...),
+		})
+	}
+
+	add(FrameTIT2, t.Title)
+	add(FrameTPE1, t.Artist)
+	add(FrameTALB, t.Album)
+	add(FrameTYER, t.Year)
+	add(FrameCOMM, t.Comment)
+
+	if t.Track != 0 {
+		add(FrameTRCK, strconv.Itoa(int(t.Track)))
+	}
+
+	add(FrameTCON, t.GenreName())
+	return frames
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+// v1Genres are the ID3v1 genres, indexed by genre byte.
+// The first 80 are from the ID3v1 specification, the
+// remainder are the Winamp extensions.
+var v1Genres = [...]string{
+	"Blues",
+	"Classic Rock",
+	"Country",
+	"Dance",
+	"Disco",
+	"Funk",
+	"Grunge",
+	"Hip-Hop",
+	"Jazz",
+	"Metal",
+	"New Age",
+	"Oldies",
+	"Other",
+	"Pop",
+	"R&B",
+	"Rap",
+	"Reggae",
+	"Rock",
+	"Techno",
+	"Industrial",
+	"Alternative",
+	"Ska",
+	"Death Metal",
+	"Pranks",
+	"Soundtrack",
+	"Euro-Techno",
+	"Ambient",
+	"Trip-Hop",
+	"Vocal",
+	"Jazz+Funk",
+	"Fusion",
+	"Trance",
+	"Classical",
+	"Instrumental",
+	"Acid",
+	"House",
+	"Game",
+	"Sound Clip",
+	"Gospel",
+	"Noise",
+	"AlternRock",
+	"Bass",
+	"Soul",
+	"Punk",
+	"Space",
+	"Meditative",
+	"Instrumental Pop",
+	"Instrumental Rock",
+	"Ethnic",
+	"Gothic",
+	"Darkwave",
+	"Techno-Industrial",
+	"Electronic",
+	"Pop-Folk",
+	"Eurodance",
+	"Dream",
+	"Southern Rock",
+	"Comedy",
+	"Cult",
+	"Gangsta",
+	"Top 40",
+	"Christian Rap",
+	"Pop/Funk",
+	"Jungle",
+	"Native American",
+	"Cabaret",
+	"New Wave",
+	"Psychadelic",
+	"Rave",
+	"Showtunes",
+	"Trailer",
+	"Lo-Fi",
+	"Tribal",
+	"Acid Punk",
+	"Acid Jazz",
+	"Polka",
+	"Retro",
+	"Musical",
+	"Rock & Roll",
+	"Hard Rock",
+	"Folk",
+	"Folk-Rock",
+	"National Folk",
+	"Swing",
+	"Fast Fusion",
+	"Bebob",
+	"Latin",
+	"Revival",
+	"Celtic",
+	"Bluegrass",
+	"Avantgarde",
+	"Gothic Rock",
+	"Progressive Rock",
+	"Psychedelic Rock",
+	"Symphonic Rock",
+	"Slow Rock",
+	"Big Band",
+	"Chorus",
+	"Easy Listening",
+	"Acoustic",
+	"Humour",
+	"Speech",
+	"Chanson",
+	"Opera",
+	"Chamber Music",
+	"Sonata",
+	"Symphony",
+	"Booty Bass",
+	"Primus",
+	"Porn Groove",
+	"Satire",
+	"Slow Jam",
+	"Club",
+	"Tango",
+	"Samba",
+	"Folklore",
+	"Ballad",
+	"Power Ballad",
+	"Rhythmic Soul",
+	"Freestyle",
+	"Duet",
+	"Punk Rock",
+	"Drum Solo",
+	"A capella",
+	"Euro-House",
+	"Dance Hall",
+	"Goa",
+	"Drum & Bass",
+	"Club-House",
+	"Hardcore",
+	"Terror",
+	"Indie",
+	"BritPop",
+	"Negerpunk",
+	"Polsk Punk",
+	"Beat",
+	"Christian Gangsta Rap",
+	"Heavy Metal",
+	"Black Metal",
+	"Crossover",
+	"Contemporary Christian",
+	"Christian Rock",
+	"Merengue",
+	"Salsa",
+	"Thrash Metal",
+	"Anime",
+	"JPop",
+	"Synthpop",
+	"Abstract",
+	"Art Rock",
+	"Baroque",
+	"Bhangra",
+	"Big Beat",
+	"Breakbeat",
+	"Chillout",
+	"Downtempo",
+	"Dub",
+	"EBM",
+	"Eclectic",
+	"Electro",
+	"Electroclash",
+	"Emo",
+	"Experimental",
+	"Garage",
+	"Global",
+	"IDM",
+	"Illbient",
+	"Industro-Goth",
+	"Jam Band",
+	"Krautrock",
+	"Leftfield",
+	"Lounge",
+	"Math Rock",
+	"New Romantic",
+	"Nu-Breakz",
+	"Post-Punk",
+	"Post-Rock",
+	"Psytrance",
+	"Shoegaze",
+	"Space Rock",
+	"Trop Rock",
+	"World Music",
+	"Neoclassical",
+	"Audiobook",
+	"Audio Theatre",
+	"Neue Deutsche Welle",
+	"Podcast",
+	"Indie Rock",
+	"G-Funk",
+	"Dubstep",
+	"Garage Rock",
+	"Psybient",
+}
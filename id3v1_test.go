@@ -0,0 +1,174 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"testing"
+)
+
+// rawV1 returns an ID3v1.1 tag, or an ID3v1 tag if track
+// is zero.
+func rawV1(title, artist, album, year, comment string, track, genre byte) []byte {
+	data := make([]byte, v1Size)
+	copy(data, "TAG")
+	copy(data[3:33], title)
+	copy(data[33:63], artist)
+	copy(data[63:93], album)
+	copy(data[93:97], year)
+	copy(data[97:127], comment)
+	if track != 0 {
+		data[125], data[126] = 0, track
+	}
+
+	data[127] = genre
+	return data
+}
+
+func TestReadV1(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want V1Tag
+	}{
+		{"v1", rawV1("Title", "Artist", "Album", "2017", "A comment that is 30 bytes lon", 0, 17),
+			V1Tag{Title: "Title", Artist: "Artist", Album: "Album", Year: "2017",
+				Comment: "A comment that is 30 bytes lon", Genre: 17}},
+		{"v1.1", rawV1("Title  ", "Artist", "", "", "Comment", 7, V1GenreNone),
+			V1Tag{Title: "Title", Artist: "Artist", Comment: "Comment", Track: 7,
+				Genre: V1GenreNone}},
+		{"latin1", rawV1("T\xeftle", "", "", "", "", 0, 0),
+			V1Tag{Title: "Tïtle"}},
+	} {
+		data := append([]byte("audio"), tc.data...)
+
+		tag, err := ReadV1(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: ReadV1 failed: %v", tc.name, err)
+		}
+
+		tc.want.Offset, tc.want.Size = 5, v1Size
+		if tag == nil || *tag != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, tag, tc.want)
+		}
+	}
+
+	for _, data := range [][]byte{nil, []byte("audio"), make([]byte, 1024)} {
+		if tag, err := ReadV1(bytes.NewReader(data), int64(len(data))); tag != nil || err != nil {
+			t.Errorf("ReadV1 returned %+v, %v for data without tag", tag, err)
+		}
+	}
+}
+
+func TestReadV1Extended(t *testing.T) {
+	title := "A title that is longer than thirty characters"
+
+	ext := make([]byte, v1ExtendedSize)
+	copy(ext, "TAG+")
+	copy(ext[4:64], title[30:])
+	ext[184] = 3
+	copy(ext[185:215], "Chiptune")
+	copy(ext[215:221], "000:05")
+	copy(ext[221:227], "003:30")
+
+	data := append([]byte("audio"), ext...)
+	data = append(data, rawV1(title[:30], "Artist", "Album", "", "", 1, 79)...)
+
+	tag, err := ReadV1(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ReadV1 failed: %v", err)
+	}
+
+	want := V1Tag{
+		Title:     title,
+		Artist:    "Artist",
+		Album:     "Album",
+		Track:     1,
+		Genre:     79,
+		Extended:  true,
+		Speed:     3,
+		FreeGenre: "Chiptune",
+		StartTime: "000:05",
+		EndTime:   "003:30",
+		Offset:    5,
+		Size:      v1Size + v1ExtendedSize,
+	}
+	if tag == nil || *tag != want {
+		t.Errorf("got %+v, want %+v", tag, want)
+	}
+
+	if name := tag.GenreName(); name != "Chiptune" {
+		t.Errorf("got genre %q, want %q", name, "Chiptune")
+	}
+}
+
+func TestV1GenreName(t *testing.T) {
+	for _, tc := range []struct {
+		genre byte
+		want  string
+	}{
+		{0, "Blues"},
+		{17, "Rock"},
+		{79, "Hard Rock"},
+		{V1GenreNone, ""},
+	} {
+		if got := (&V1Tag{Genre: tc.genre}).GenreName(); got != tc.want {
+			t.Errorf("genre %d: got %q, want %q", tc.genre, got, tc.want)
+		}
+	}
+}
+
+func TestV1Frames(t *testing.T) {
+	tag := &V1Tag{
+		Title:   "Title",
+		Artist:  "Ärtist",
+		Comment: "Comment",
+		Track:   12,
+		Genre:   17,
+	}
+
+	frames := tag.Frames()
+	if len(frames) != 5 {
+		t.Fatalf("got %d frames, want 5", len(frames))
+	}
+
+	checkText(t, frames, FrameTIT2, "Title")
+	checkText(t, frames, FrameTPE1, "Ärtist")
+	checkText(t, frames, FrameTRCK, "12")
+	checkText(t, frames, FrameTCON, "Rock")
+
+	if comm := frames.Lookup(FrameCOMM); comm == nil || string(comm.Data) != "\x00XXX\x00Comment" {
+		t.Errorf("got COMM frame %v", comm)
+	}
+
+	// Characters outside of ISO-8859-1 are written as
+	// UTF-16.
+	tag.Title = "Tit€e"
+	if tit2 := tag.Frames().Lookup(FrameTIT2); tit2.Data[0] != textEncodingUTF16 {
+		t.Errorf("got TIT2 frame %v", tit2)
+	}
+
+	checkText(t, tag.Frames(), FrameTIT2, "Tit€e")
+}
+
+func TestScanFileFallbackV1(t *testing.T) {
+	data := append([]byte("audio"), rawV1("Title", "Artist", "", "", "", 0, V1GenreNone)...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	frames, err := ScanFile(path)
+	if err != nil || len(frames) != 0 {
+		t.Errorf("ScanFile returned %v, %v without FallbackV1", frames, err)
+	}
+
+	frames, err = (&ScanOptions{FallbackV1: true}).ScanFile(path)
+	if err != nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+
+	checkText(t, frames, FrameTIT2, "Title")
+	checkText(t, frames, FrameTPE1, "Artist")
+}
@@ -0,0 +1,335 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NewV1Tag returns an ID3v1.1 tag holding the values of
+// the TIT2, TPE1, TALB, TYER or TDRC, COMM, TRCK and TCON
+// frames.
+//
+// The genre is the ID3v1 genre matching TCON, or
+// V1GenreNone if there is no match. The track is the
+// track number from TRCK, or zero if it does not fit in
+// a single byte.
+func NewV1Tag(frames Frames) (*V1Tag, error) {
+	tag := &V1Tag{Genre: V1GenreNone}
+
+	for _, field := range [...]struct {
+		id  FrameID
+		val *string
+	}{
+		{FrameTIT2, &tag.Title},
+		{FrameTPE1, &tag.Artist},
+		{FrameTALB, &tag.Album},
+		{FrameTDRC, &tag.Year},
+		{FrameTYER, &tag.Year},
+	} {
+		frame := frames.Lookup(field.id)
+		if frame == nil {
+			continue
+		}
+
+		text, err := frame.Text()
+		if err != nil {
+			return nil, err
+		}
+
+		*field.val = firstValue(text)
+	}
+
+	if len(tag.Year) > 4 {
+		tag.Year = tag.Year[:4]
+	}
+
+	comment, err := v1Comment(frames)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Comment = comment
+
+	if frame := frames.Lookup(FrameTRCK); frame != nil {
+		text, err := frame.Text()
+		if err != nil {
+			return nil, err
+		}
+
+		// TRCK may also contain the total number of tracks
+		// in the form 3/12.
+		if i := strings.IndexByte(text, '/'); i != -1 {
+			text = text[:i]
+		}
+
+		if n, err := strconv.ParseUint(strings.TrimSpace(text), 10, 8); err == nil {
+			tag.Track = byte(n)
+		}
+	}
+
+	if frame := frames.Lookup(FrameTCON); frame != nil {
+		text, err := frame.Text()
+		if err != nil {
+			return nil, err
+		}
+
+		tag.Genre = v1Genre(firstValue(text))
+	}
+
+	return tag, nil
+}
+
+// firstValue returns the first of the zero separated
+// strings in a v2.4.0 text frame.
+func firstValue(text string) string {
+	if i := strings.IndexByte(text, 0x00); i != -1 {
+		return text[:i]
+	}
+
+	return text
+}
+
+// v1Comment returns the text of the first COMM frame
+// without a content descriptor, or of the first COMM
+// frame if they all have descriptors.
+func v1Comment(frames Frames) (string, error) {
+	var comment string
+	var found bool
+	for _, frame := range frames {
+		if frame.ID != FrameCOMM {
+			continue
+		}
+
+		desc, text, err := splitComment(frame)
+		if err != nil {
+			return "", err
+		}
+
+		if desc == "" {
+			return text, nil
+		}
+
+		if !found {
+			comment, found = text, true
+		}
+	}
+
+	return comment, nil
+}
+
+// splitComment returns the content descriptor and text
+// of a COMM frame, according to §4.10 of
+// id3v2.4.0-frames.txt.
+func splitComment(f *Frame) (desc, text string, err error) {
+	if err := f.Load(); err != nil {
+		return "", "", err
+	}
+
+	if len(f.Data) < 4 {
+		return "", "", ErrInvalidFrameData
+	}
+
+	enc, data := f.Data[0], f.Data[4:]
+
+	terminator := zeroByte
+	if enc == textEncodingUTF16 || enc == textEncodingUTF16BE {
+		terminator = zeroBytes
+	}
+
+	i := indexTerminator(data, terminator)
+	if i == -1 {
+		return "", "", ErrInvalidFrameData
+	}
+
+	// Both strings share the text encoding of the frame,
+	// so each can be decoded as if it were a text frame.
+	// Empty UTF-16 strings often lack a BOM.
+	decode := func(str []byte) (string, error) {
+		if len(str) == 0 {
+			return "", nil
+		}
+
+		return (&Frame{
+			ID:      f.ID,
+			Version: f.Version,
+			Flags:   f.Flags,
+			Data:    append([]byte{enc}, str...),
+		}).Text()
+	}
+
+	if desc, err = decode(data[:i]); err != nil {
+		return "", "", err
+	}
+
+	if text, err = decode(data[i+len(terminator):]); err != nil {
+		return "", "", err
+	}
+
+	return desc, text, nil
+}
+
+// v1Genre returns the ID3v1 genre index for the genre
+// name, or for a v2.3.0 "(17)" or v2.4.0 "17" genre
+// reference.
+func v1Genre(name string) byte {
+	ref := name
+	if strings.HasPrefix(ref, "(") {
+		if i := strings.IndexByte(ref, ')'); i != -1 {
+			ref = ref[1:i]
+		}
+	}
+
+	if n, err := strconv.ParseUint(ref, 10, 8); err == nil && n < uint64(len(v1Genres)) {
+		return byte(n)
+	}
+
+	for i, genre := range v1Genres {
+		if strings.EqualFold(genre, name) {
+			return byte(i)
+		}
+	}
+
+	return V1GenreNone
+}
+
+// MarshalBinary encodes the tag as a 128 byte ID3v1.1
+// tag, or an ID3v1 tag if Track is zero. The enhanced
+// block is never written.
+//
+// Characters that cannot be represented in ISO-8859-1
+// are transliterated where possible, and replaced with
+// '?' otherwise. Fields that are too long are truncated.
+func (t *V1Tag) MarshalBinary() ([]byte, error) {
+	data := make([]byte, v1Size)
+	copy(data, "TAG")
+	copy(data[3:33], v1Bytes(t.Title))
+	copy(data[33:63], v1Bytes(t.Artist))
+	copy(data[63:93], v1Bytes(t.Album))
+	copy(data[93:97], v1Bytes(t.Year))
+
+	if t.Track != 0 {
+		copy(data[97:125], v1Bytes(t.Comment))
+		data[126] = t.Track
+	} else {
+		copy(data[97:127], v1Bytes(t.Comment))
+	}
+
+	data[127] = t.Genre
+	return data, nil
+}
+
+// v1Transliterations are replacements for common
+// characters that ISO-8859-1 cannot represent.
+var v1Transliterations = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '′': "'",
+	'“': `"`, '”': `"`, '„': `"`, '″': `"`,
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+	'…': "...", '•': "*", '€': "EUR",
+	'Œ': "OE", 'œ': "oe", 'Ł': "L", 'ł': "l",
+	'Đ': "D", 'đ': "d", 'ı': "i",
+}
+
+// v1Bytes encodes the string as ISO-8859-1.
+func v1Bytes(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range norm.NFC.String(s) {
+		if r < 0x100 {
+			out = append(out, byte(r))
+			continue
+		}
+
+		if repl, ok := v1Transliterations[r]; ok {
+			out = append(out, repl...)
+			continue
+		}
+
+		// Fall back to the base character of a decomposed
+		// character, such as 'c' for 'č'.
+		if base := []rune(norm.NFD.String(string(r)))[0]; base < 0x100 {
+			out = append(out, byte(base))
+		} else {
+			out = append(out, '?')
+		}
+	}
+
+	return out
+}
+
+// UpdateV1File writes an ID3v1.1 tag created from the
+// frames by NewV1Tag to the end of the file. Any existing
+// ID3v1 tag, including any enhanced block, is replaced
+// in place.
+//
+// UpdateV1File uses the default options. It is
+// equivalent to calling UpdateV1File on a zero
+// UpdateOptions.
+func UpdateV1File(path string, frames Frames) error {
+	return new(UpdateOptions).UpdateV1File(path, frames)
+}
+
+// UpdateV1File is like the package level UpdateV1File
+// but uses the options in o.
+func (o *UpdateOptions) UpdateV1File(path string, frames Frames) error {
+	tag, err := NewV1Tag(frames)
+	if err != nil {
+		return err
+	}
+
+	data, err := tag.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := fi.Size()
+
+	old, err := ReadV1(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	if old != nil {
+		offset = old.Offset
+	}
+
+	if _, err := f.WriteAt(data, offset); err != nil {
+		return err
+	}
+
+	if err := f.Truncate(offset + int64(len(data))); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return o.restoreModTime(path, fi)
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestNewV1Tag(t *testing.T) {
+	frames := Frames{
+		{ID: FrameTIT2, Version: Version24, Data: []byte("\x03Title\x00Subtitle")},
+		{ID: FrameTPE1, Version: Version24, Data: textData("Artist")},
+		{ID: FrameTDRC, Version: Version24, Data: textData("2017-01-01")},
+		{ID: FrameCOMM, Version: Version24, Data: []byte("\x03engdesc\x00described")},
+		{ID: FrameCOMM, Version: Version24, Data: []byte("\x01eng\x00\x00\xff\xfec\x00o\x00m\x00m\x00e\x00n\x00t\x00")},
+		{ID: FrameTRCK, Version: Version24, Data: textData("3/12")},
+		{ID: FrameTCON, Version: Version24, Data: textData("Hard Rock")},
+	}
+
+	tag, err := NewV1Tag(frames)
+	if err != nil {
+		t.Fatalf("NewV1Tag failed: %v", err)
+	}
+
+	want := V1Tag{
+		Title:   "Title",
+		Artist:  "Artist",
+		Year:    "2017",
+		Comment: "comment",
+		Track:   3,
+		Genre:   79,
+	}
+	if *tag != want {
+		t.Errorf("got %+v, want %+v", tag, want)
+	}
+}
+
+func TestV1Genre(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want byte
+	}{
+		{"(17)", 17},
+		{"(17)Rock", 17},
+		{"17", 17},
+		{"rock", 17},
+		{"Hard Rock", 79},
+		{"Chiptune", V1GenreNone},
+		{"255", V1GenreNone},
+	} {
+		if got := v1Genre(tc.name); got != tc.want {
+			t.Errorf("v1Genre(%q) = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestV1MarshalBinary(t *testing.T) {
+	tag := &V1Tag{
+		Title:   "Čeština – “quoted”",
+		Artist:  "A very long artist name that does not fit",
+		Comment: "Comment",
+		Track:   9,
+		Genre:   17,
+	}
+
+	data, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	if len(data) != v1Size {
+		t.Fatalf("got %d bytes, want %d", len(data), v1Size)
+	}
+
+	got, err := ReadV1(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ReadV1 failed: %v", err)
+	}
+
+	want := V1Tag{
+		Title:   `Cestina - "quoted"`,
+		Artist:  tag.Artist[:30],
+		Comment: "Comment",
+		Track:   9,
+		Genre:   17,
+		Size:    v1Size,
+	}
+	if *got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateV1File(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("Title")}}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"append", []byte("audio")},
+		{"replace", append([]byte("audio"), rawV1("Old", "Old", "", "", "", 0, 0)...)},
+	} {
+		path, cleanup := writeTempFile(t, tc.data)
+		defer cleanup()
+
+		if err := UpdateV1File(path, frames); err != nil {
+			t.Fatalf("%s: UpdateV1File failed: %v", tc.name, err)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := append([]byte("audio"), rawV1("Title", "", "", "", "", 0, V1GenreNone)...)
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: got %q, want %q", tc.name, data, want)
+		}
+	}
+}
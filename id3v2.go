@@ -2,7 +2,7 @@
 // Use of this source code is governed by a Modified
 // BSD License that can be found in the LICENSE file.
 
-// Package id3v2 implements support for reading ID3v2 tags.
+// Package id3v2 implements support for reading and writing ID3v2 tags.
 package id3v2
 
 //go:generate go run generate_ids.go
@@ -25,6 +25,8 @@ import (
 // This is an implementation of v2.4.0 of the ID3v2 tagging format,
 // defined in: http://id3.org/id3v2.4.0-structure, and v2.3.0 of
 // the ID3v2 tagging format, defined in: http://id3.org/id3v2.3.0.
+// Reading of v2.2.0 of the ID3v2 tagging format, defined in:
+// http://id3.org/id3v2-00, is also supported.
 
 // Version is the version of the ID3v2 tag block.
 type Version byte
@@ -34,18 +36,32 @@ const (
 	Version24 Version = 0x04
 	// Version23 is v2.3.x of the ID3v2 specification.
 	Version23 Version = 0x03
+	// Version22 is v2.2.x of the ID3v2 specification.
+	Version22 Version = 0x02
 )
 
+// TagFlags are the tag-level ID3v2 flags.
+type TagFlags byte
+
+// These are the tag-level flags from v2.4.0 of the
+// specification. TagFlagFooter is not defined by v2.3.0.
 const (
-	tagFlagUnsynchronisation = 1 << (7 - iota)
-	tagFlagExtendedHeader
-	tagFlagExperimental
-	tagFlagFooter
+	TagFlagUnsynchronisation TagFlags = 1 << (7 - iota)
+	TagFlagExtendedHeader
+	TagFlagExperimental
+	TagFlagFooter
 
-	knownTagFlags = tagFlagUnsynchronisation | tagFlagExtendedHeader |
-		tagFlagExperimental | tagFlagFooter
+	knownTagFlags = TagFlagUnsynchronisation | TagFlagExtendedHeader |
+		TagFlagExperimental | TagFlagFooter
 )
 
+// TagFlagV22Compression is the compression flag from
+// v2.2.0 of the specification. It shares its value with
+// TagFlagExtendedHeader.
+const TagFlagV22Compression = TagFlagExtendedHeader
+
+const knownTagFlagsV22 = TagFlagUnsynchronisation | TagFlagV22Compression
+
 // FrameFlags are the frame-level ID3v2 flags.
 type FrameFlags uint16
 
@@ -112,28 +128,20 @@ func syncsafe(data []byte) uint32 {
 
 var id3Token = []byte("ID3")
 
-func id3Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	i := bytes.Index(data, id3Token)
-	if i == -1 {
-		if len(data) < 2 {
-			return 0, nil, nil
-		}
+// tagSize returns the size of the tag, including the
+// header and any footer, that begins with header. It
+// returns -1 if header is not the header of a tag this
+// package supports.
+func tagSize(header []byte) int {
+	_ = header[9]
 
-		return len(data) - 2, nil, nil
+	if string(header[:3]) != "ID3" {
+		return -1
 	}
 
-	data = data[i:]
-	if len(data) < 10 {
-		if atEOF {
-			return 0, nil, io.ErrUnexpectedEOF
-		}
-
-		return i, nil, nil
-	}
+	size := syncsafe(header[6:])
 
-	size := syncsafe(data[6:])
-
-	if data[3] == 0xff || data[4] == 0xff || size == syncsafeInvalid {
+	if header[3] == 0xff || header[4] == 0xff || size == syncsafeInvalid {
 		// Skipping when we find the string "ID3" in the file but
 		// the remaining header is invalid is consistent with the
 		// detection logic in §3.1. This also reduces the
@@ -146,46 +154,88 @@ func id3Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		//     $49 44 33 yy yy xx zz zz zz zz
 		//   Where yy is less than $FF, xx is the 'flags' byte and zz
 		//   is less than $80.
-		return i + 3, nil, nil
+		return -1
 	}
 
-	if Version(data[3]) > Version24 {
+	if Version(header[3]) > Version24 {
 		// Quoting from §3.1 of id3v2.4.0-structure.txt:
 		//   If software with ID3v2.4.0 and below support should
 		//   encounter version five or higher it should simply
 		//   ignore the whole tag.
-		return i + 3, nil, nil
+		return -1
+	}
+
+	if Version(header[3]) < Version22 {
+		// This package only supports v2.2.0, v2.3.0 and
+		// v2.4.0, skip versions bellow v2.2.0.
+		return -1
 	}
 
-	if Version(data[3]) < Version23 {
-		// This package only supports v2.3.0 and v2.4.0, skip
-		// versions bellow v2.3.0.
-		return i + 3, nil, nil
+	known := knownTagFlags
+	if Version(header[3]) == Version22 {
+		known = knownTagFlagsV22
 	}
 
-	if data[5]&^knownTagFlags != 0 {
+	if TagFlags(header[5])&^known != 0 {
 		// Skip tag blocks that contain unknown flags.
 		//
 		// Quoting from §3.1 of id3v2.4.0-structure.txt:
 		//   If one of these undefined flags are set, the tag might
 		//   not be readable for a parser that does not know the
 		//   flags function.
-		return i + 3, nil, nil
+		return -1
 	}
 
-	if data[5]&tagFlagFooter == tagFlagFooter {
+	if TagFlags(header[5])&TagFlagFooter == TagFlagFooter {
 		size += 10
 	}
 
-	if len(data) < 10+int(size) {
-		if atEOF {
-			return 0, nil, io.ErrUnexpectedEOF
+	return 10 + int(size)
+}
+
+// id3Split is a bufio.SplitFunc that returns each tag in
+// the stream as a token. If the stream ends part way
+// through a tag, it returns io.ErrUnexpectedEOF with the
+// offset of the tag as advance.
+func id3Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for {
+		i := bytes.Index(data[advance:], id3Token)
+		if i == -1 {
+			if len(data)-advance < 2 {
+				return advance, nil, nil
+			}
+
+			return len(data) - 2, nil, nil
 		}
 
-		return i, nil, nil
-	}
+		i += advance
+		if len(data)-i < 10 {
+			if atEOF {
+				return i, nil, io.ErrUnexpectedEOF
+			}
+
+			return i, nil, nil
+		}
+
+		size := tagSize(data[i:])
+		if size == -1 {
+			// Keep searching rather than returning without a
+			// token, as bufio.Scanner stops at EOF if no token
+			// is returned.
+			advance = i + 3
+			continue
+		}
 
-	return i + 10 + int(size), data[:10+size], nil
+		if len(data)-i < size {
+			if atEOF {
+				return i, nil, io.ErrUnexpectedEOF
+			}
+
+			return i, nil, nil
+		}
+
+		return i + size, data[i : i+size], nil
+	}
 }
 
 const invalidFrameID = ^FrameID(0)
@@ -214,6 +264,11 @@ func frameID(data []byte) FrameID {
 	return invalidFrameID
 }
 
+// maxTokenSize is large enough to hold the largest tag
+// permitted by the specification, including its header
+// and footer.
+const maxTokenSize = 20 + 1<<28
+
 var bufPool = &sync.Pool{
 	New: func() interface{} {
 		buf := make([]byte, 4<<10)
@@ -221,162 +276,364 @@ var bufPool = &sync.Pool{
 	},
 }
 
+// ScanMode controls how strictly tags are validated
+// while scanning.
+type ScanMode int
+
+const (
+	// ScanDefault rejects malformed tags, but records
+	// problems that do not prevent a tag from being read,
+	// such as a CRC mismatch, on the Tag.
+	ScanDefault ScanMode = iota
+
+	// ScanStrict rejects any tag that fails validation.
+	ScanStrict
+
+	// ScanLenient works around common encoder bugs where
+	// possible and skips the parts of a tag that cannot be
+	// read, such as an invalid extended header or the end
+	// of a truncated tag, recording a Warning on the Tag
+	// for each problem. The frames that were read are
+	// still returned.
+	ScanLenient
+)
+
+// ScanOptions are the options used when scanning for
+// ID3v2 tags. The zero value is ready to use.
+type ScanOptions struct {
+	// Mode controls how strictly tags are validated.
+	Mode ScanMode
+
+	// Decrypters are used to decrypt encrypted frames. They
+	// are keyed by the owner identifier of the encryption
+	// method registered by an ENCR frame. Frames encrypted
+	// with other methods are left encrypted.
+	Decrypters map[string]Decrypter
+
+	// FallbackV1 causes ScanFile to return frames
+	// synthesised from the ID3v1 tag at the end of the file
+	// if the file does not contain an ID3v2 tag.
+	FallbackV1 bool
+
+	// SkipAudio causes ScanFile to only read the tags at
+	// the locations defined by the specification, as
+	// ScanTagsAt does, rather than searching the whole
+	// file for tags.
+	SkipAudio bool
+
+	// Lazy causes ScanTagsAt to leave the data of frames
+	// without any frame-level encodings unread. It is read
+	// on demand by Frame.Load or Frame.Open, or when it is
+	// first interpreted, such as by Frame.Text, so the
+	// io.ReaderAt must remain usable until then. The CRC in
+	// the extended header is not verified.
+	//
+	// Lazy is ignored by Scan, ScanTags, ScanFile and
+	// NewReader, which always read the frame data. ScanFile
+	// closes the file before it returns.
+	Lazy bool
+
+	// MaxTagSize, if non-zero, is the size in bytes of the
+	// largest tag that is read. Larger tags fail with
+	// ErrTagSizeLimit before they are read.
+	MaxTagSize int64
+
+	// MaxFrameSize, if non-zero, is the size in bytes of
+	// the largest frame that is read. Larger frames fail
+	// with ErrFrameSizeLimit before they are read.
+	MaxFrameSize int64
+
+	// MaxFrames, if non-zero, is the largest number of
+	// frames read from a single tag. Tags with more frames
+	// fail with ErrFrameCountLimit.
+	MaxFrames int
+
+	// MaxDecompressedSize, if non-zero, is the size in bytes
+	// of the largest frame that is decompressed. Larger
+	// frames fail with ErrDecompressedSizeLimit before they
+	// are decompressed.
+	MaxDecompressedSize int64
+}
+
+// checkTagSize returns an error if the tag of size bytes
+// at offset exceeds MaxTagSize.
+func (o *ScanOptions) checkTagSize(index int, offset, size int64) error {
+	if o.MaxTagSize <= 0 || size <= o.MaxTagSize {
+		return nil
+	}
+
+	return &TagError{
+		Offset:   offset,
+		TagIndex: index,
+		Err:      ErrTagSizeLimit,
+	}
+}
+
 // Scan reads all valid ID3v2 tags from the reader and
 // returns all the frames in order. It returns an error
 // if the tags are invalid.
+//
+// Scan uses the default options. It is equivalent to
+// calling Scan on a zero ScanOptions.
 func Scan(r io.Reader) (Frames, error) {
-	buf := bufPool.Get().(*[]byte)
-	defer bufPool.Put(buf)
+	return new(ScanOptions).Scan(r)
+}
 
-	s := bufio.NewScanner(r)
-	s.Buffer(*buf, 20+1<<28)
-	s.Split(id3Split)
+// Scan is like the package level Scan but uses the
+// options in o.
+func (o *ScanOptions) Scan(r io.Reader) (Frames, error) {
+	tags, err := o.ScanTags(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return tagFrames(tags), nil
+}
 
+// tagFrames returns the frames of every tag in order.
+func tagFrames(tags []*Tag) Frames {
 	var frames Frames
+	for _, tag := range tags {
+		frames = append(frames, tag.Frames...)
+	}
 
-	for s.Scan() {
-		data := s.Bytes()
+	return frames
+}
 
-		header := data[:10]
-		data = data[10:]
+// ScanTags reads all valid ID3v2 tags from the reader
+// and returns them in order. It returns an error if the
+// tags are invalid.
+//
+// If a tag has a SEEK frame, the data between it and the
+// next tag is skipped without being searched for tags.
+//
+// ScanTags uses the default options. It is equivalent
+// to calling ScanTags on a zero ScanOptions.
+func ScanTags(r io.Reader) ([]*Tag, error) {
+	return new(ScanOptions).ScanTags(r)
+}
 
-		if string(header[:3]) != "ID3" {
-			panic("id3: bufio.Scanner failed")
-		}
+// ScanTags is like the package level ScanTags but uses
+// the options in o.
+func (o *ScanOptions) ScanTags(r io.Reader) ([]*Tag, error) {
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
 
-		version := Version(header[3])
-		switch version {
-		case Version24, Version23:
-		default:
-			panic("id3: bufio.Scanner failed")
-		}
+	var tags []*Tag
+	var offset, tagOffset, skip int64
 
-		flags := header[5]
+	s := bufio.NewScanner(r)
+	s.Buffer(*buf, maxTokenSize)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		var skipped int
+		if skip > 0 {
+			// Skip over the audio between a tag and the tag
+			// its SEEK frame points to.
+			n := skip
+			if n > int64(len(data)) {
+				n = int64(len(data))
+			}
 
-		if flags&tagFlagFooter == tagFlagFooter {
-			footer := data[len(data)-10:]
-			data = data[:len(data)-10]
+			offset += n
+			skip -= n
 
-			if string(footer[:3]) != "3DI" ||
-				!bytes.Equal(header[3:], footer[3:]) {
-				return nil, errors.New("id3: invalid footer")
+			if skip > 0 {
+				return int(n), nil, nil
 			}
+
+			skipped, data = int(n), data[n:]
 		}
 
-		if flags&tagFlagExtendedHeader == tagFlagExtendedHeader {
-			var size uint32
-			switch version {
-			case Version24:
-				size = syncsafe(data)
-				if size == syncsafeInvalid {
-					return nil, errors.New("id3: invalid extended header size")
+		advance, token, err := id3Split(data, atEOF)
+		if err == io.ErrUnexpectedEOF {
+			if o.Mode != ScanLenient {
+				return 0, nil, &TagError{
+					Offset:   offset + int64(advance),
+					TagIndex: len(tags),
+					Err:      ErrTruncatedTag,
 				}
-			case Version23:
-				size = binary.BigEndian.Uint32(data) + 4
-			default:
-				panic("unhandled version")
 			}
 
-			if len(data) < int(size) {
-				return nil, errors.New("id3: invalid extended header size")
+			// In lenient mode, as many frames as possible are
+			// read from a truncated tag. A partial tag header
+			// cannot be told apart from audio and is ignored.
+			if len(data)-advance >= 10 {
+				token = data[advance:]
 			}
 
-			extendedHeader := data[:size]
-			data = data[size:]
-
-			_ = extendedHeader
+			advance, err = len(data), nil
 		}
 
-	frames:
-		for len(data) > 10 {
-			_ = data[9]
-
-			frame := &Frame{
-				ID:      frameID(data),
-				Version: version,
-				Flags:   FrameFlags(binary.BigEndian.Uint16(data[8:])),
-			}
+		if token != nil {
+			tagOffset = offset + int64(advance-len(token))
 
-			switch frame.ID {
-			case 0:
-				// We've probably hit padding, the padding
-				// validity check below will handle this.
-				break frames
-			case invalidFrameID:
-				return nil, errors.New("id3: invalid frame id")
+			// The token is shorter than the tag if the tag is
+			// truncated.
+			if err := o.checkTagSize(len(tags), tagOffset, int64(tagSize(token))); err != nil {
+				return 0, nil, err
 			}
-
-			var size uint32
-			switch version {
-			case Version24:
-				size = syncsafe(data[4:])
-				if size == syncsafeInvalid {
-					return nil, errors.New("id3: invalid frame size")
+		} else if err == nil && len(data)-advance >= 10 {
+			// id3Split is waiting for the rest of the tag at
+			// advance. It must be rejected before it is read
+			// into the buffer.
+			if size := tagSize(data[advance:]); size != -1 {
+				if err := o.checkTagSize(len(tags), offset+int64(advance), int64(size)); err != nil {
+					return 0, nil, err
 				}
-			case Version23:
-				size = binary.BigEndian.Uint32(data[4:])
-			default:
-				panic("unhandled version")
-			}
-
-			if len(data) < 10+int(size) {
-				return nil, errors.New("id3: frame size exceeds length of tag data")
 			}
+		}
 
-			if flags&tagFlagUnsynchronisation == tagFlagUnsynchronisation ||
-				version == Version24 && frame.Flags&FrameFlagV24Unsynchronisation != 0 {
-				frame.Data = make([]byte, 0, size)
+		offset += int64(advance)
+		return skipped + advance, token, err
+	})
 
-				for i := uint32(0); i < size; i++ {
-					v := data[10+i]
-					frame.Data = append(frame.Data, v)
+	for s.Scan() {
+		data := s.Bytes()
+		tag, next, err := o.readTag(data[:10], bytes.NewReader(data[10:]), len(tags), tagOffset, int64(tagSize(data)), nil)
+		if err != nil {
+			return nil, err
+		}
 
-					if v == 0xff && i+1 < size && data[10+i+1] == 0x00 {
-						i++
-					}
-				}
+		tags = append(tags, tag)
 
-				if version == Version24 {
-					// Clear the frame level unsynchronisation flag
-					frame.Flags &^= FrameFlagV24Unsynchronisation
-				}
-			} else {
-				frame.Data = append([]byte(nil), data[10:10+size]...)
-			}
-
-			frames = append(frames, frame)
-			data = data[10+size:]
+		if next != -1 {
+			skip = next - offset
 		}
+	}
 
-		if flags&tagFlagFooter == tagFlagFooter && len(data) != 0 {
-			return nil, errors.New("id3: padding with footer")
+	if err := s.Err(); err == bufio.ErrTooLong {
+		// The scanner buffer is large enough for any tag, so
+		// this should never happen.
+		return nil, &TagError{
+			Offset:   offset,
+			TagIndex: len(tags),
+			Err:      ErrTagSizeLimit,
 		}
+	} else if err != nil {
+		return nil, err
+	}
 
-		for _, v := range data {
-			if v != 0 {
-				return nil, errors.New("id3: invalid padding")
-			}
+	return tags, nil
+}
+
+// readAt reads n bytes from r at offset off.
+func readAt(r io.ReaderAt, off, n int64) ([]byte, error) {
+	data := make([]byte, n)
+	if m, err := r.ReadAt(data, off); m != len(data) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
+
+		return nil, err
 	}
 
-	if s.Err() != nil {
-		return nil, s.Err()
+	return data, nil
+}
+
+// resynchronise reverses the unsynchronisation scheme
+// described in §6.1 of id3v2.4.0-structure.txt.
+func resynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+
+	for i := 0; i < len(data); i++ {
+		v := data[i]
+		out = append(out, v)
+
+		if v == 0xff && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
 	}
 
-	return frames, nil
+	return out
 }
 
 // ScanFile reads all valid ID3v2 tags from a file and
 // returns all the frames in order. It returns an error
 // if the tags are invalid, or the file cannot be opened.
+//
+// ScanFile uses the default options. It is equivalent
+// to calling ScanFile on a zero ScanOptions.
 func ScanFile(path string) (Frames, error) {
+	return new(ScanOptions).ScanFile(path)
+}
+
+// ScanFile is like the package level ScanFile but uses
+// the options in o.
+func (o *ScanOptions) ScanFile(path string) (Frames, error) {
+	if o.Lazy {
+		// The frames could not be loaded once the file has
+		// been closed.
+		so := *o
+		so.Lazy = false
+		o = &so
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	return Scan(f)
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*Tag
+	if o.SkipAudio {
+		tags, err = o.ScanTagsAt(f, fi.Size())
+	} else {
+		tags, err = o.ScanTags(f)
+	}
+
+	if err != nil || len(tags) != 0 || !o.FallbackV1 {
+		return tagFrames(tags), err
+	}
+
+	v1, err := ReadV1(f, fi.Size())
+	if v1 == nil || err != nil {
+		return nil, err
+	}
+
+	return v1.Frames(), nil
+}
+
+// Tag is a single ID3v2 tag.
+type Tag struct {
+	Version  Version
+	Revision byte
+	Flags    TagFlags
+
+	// ExtendedHeader is the extended header of the tag,
+	// or nil if the tag does not have one.
+	ExtendedHeader *ExtendedHeader
+
+	// Offset is the position of the tag header relative
+	// to the start of the reader.
+	Offset int64
+
+	// Size is the total size of the tag, including the
+	// header, padding and footer.
+	Size int64
+
+	// Padding is the number of padding bytes that follow
+	// the last frame.
+	Padding int64
+
+	// CRCMismatch is set if the CRC-32 in the extended
+	// header does not match the tag data.
+	CRCMismatch bool
+
+	// Warnings are the recoverable problems found while
+	// reading the tag in lenient mode.
+	Warnings []Warning
+
+	Frames Frames
+}
+
+// HasFooter reports whether the tag ends with a footer.
+func (t *Tag) HasFooter() bool {
+	return t.Flags&TagFlagFooter == TagFlagFooter
 }
 
 // Frames is a slice of ID3v2 frames.
@@ -395,11 +652,50 @@ func (f Frames) Lookup(id FrameID) *Frame {
 }
 
 // Frame is a single ID3v2 frame.
+//
+// Frames read from v2.2.0 tags use the equivalent v2.3.0
+// frame id, but otherwise keep the v2.2.0 data layout,
+// except for PIC frames which are converted to APIC.
 type Frame struct {
 	ID      FrameID
 	Version Version
 	Flags   FrameFlags
-	Data    []byte
+
+	// GroupID is the group identifier of the frame. It is
+	// only meaningful if the grouping identity flag is
+	// set.
+	GroupID byte
+
+	// EncryptionMethod is the symbol of the encryption
+	// method, registered by an ENCR frame, that the frame
+	// is encrypted with. It is only meaningful if the
+	// encryption flag is set.
+	EncryptionMethod byte
+
+	// Offset is the position of the frame data, following
+	// the frame header, relative to the start of the
+	// reader. Size is the length of the frame data as
+	// stored in the tag, before any frame-level encodings
+	// are reversed. For v2.3.0 and v2.2.0 tags that use
+	// unsynchronisation, both are relative to the
+	// resynchronised tag data.
+	Offset int64
+	Size   int64
+
+	// DataLength is the length of the frame data once all
+	// frame-level encodings have been reversed. It is read
+	// from the data length indicator, or the decompressed
+	// size for v2.3.0, and is written when the frame is
+	// still compressed.
+	DataLength uint32
+
+	// Data is the frame data. It is nil until Load is
+	// called for frames read with the Lazy option.
+	Data []byte
+
+	// r is the reader that Data is read from by Load, or
+	// nil once it has been read.
+	r io.ReaderAt
 }
 
 func (f *Frame) String() string {
@@ -409,6 +705,8 @@ func (f *Frame) String() string {
 		version = "v2.4"
 	case Version23:
 		version = "v2.3"
+	case Version22:
+		version = "v2.2"
 	}
 
 	data, terminus := f.Data, ""
@@ -428,11 +726,15 @@ var (
 // Text interprets the frame data as a text string,
 // according to §4 of id3v2.4.0-structure.txt.
 func (f *Frame) Text() (string, error) {
+	if err := f.Load(); err != nil {
+		return "", err
+	}
+
 	if len(f.Data) == 0 {
-		return "", errors.New("id3: frame data is invalid")
+		return "", ErrInvalidFrameData
 	}
 
-	if f.Flags&encodingFrameFlags != 0 {
+	if f.encoded() {
 		return "", errors.New("id3: encoding frame flags are not supported")
 	}
 
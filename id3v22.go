@@ -0,0 +1,140 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import "strings"
+
+// v22FrameIDs maps the three character frame ids of
+// v2.2.0 to their v2.3.0 equivalents.
+//
+// Taken from http://id3.org/id3v2-00 §4 and §4.19 of
+// http://id3.org/id3v2.3.0.
+var v22FrameIDs = map[string]FrameID{
+	"BUF": FrameRBUF,
+	"CNT": FramePCNT,
+	"COM": FrameCOMM,
+	"CRA": FrameAENC,
+	"EQU": FrameEQUA,
+	"ETC": FrameETCO,
+	"GEO": FrameGEOB,
+	"IPL": FrameIPLS,
+	"LNK": FrameLINK,
+	"MCI": FrameMCDI,
+	"MLL": FrameMLLT,
+	"PIC": FrameAPIC,
+	"POP": FramePOPM,
+	"REV": FrameRVRB,
+	"RVA": FrameRVAD,
+	"SLT": FrameSYLT,
+	"STC": FrameSYTC,
+	"TAL": FrameTALB,
+	"TBP": FrameTBPM,
+	"TCM": FrameTCOM,
+	"TCO": FrameTCON,
+	"TCR": FrameTCOP,
+	"TDA": FrameTDAT,
+	"TDY": FrameTDLY,
+	"TEN": FrameTENC,
+	"TFT": FrameTFLT,
+	"TIM": FrameTIME,
+	"TKE": FrameTKEY,
+	"TLA": FrameTLAN,
+	"TLE": FrameTLEN,
+	"TMT": FrameTMED,
+	"TOA": FrameTOPE,
+	"TOF": FrameTOFN,
+	"TOL": FrameTOLY,
+	"TOR": FrameTORY,
+	"TOT": FrameTOAL,
+	"TP1": FrameTPE1,
+	"TP2": FrameTPE2,
+	"TP3": FrameTPE3,
+	"TP4": FrameTPE4,
+	"TPA": FrameTPOS,
+	"TPB": FrameTPUB,
+	"TRC": FrameTSRC,
+	"TRD": FrameTRDA,
+	"TRK": FrameTRCK,
+	"TSI": FrameTSIZ,
+	"TSS": FrameTSSE,
+	"TT1": FrameTIT1,
+	"TT2": FrameTIT2,
+	"TT3": FrameTIT3,
+	"TXT": FrameTEXT,
+	"TXX": FrameTXXX,
+	"TYE": FrameTYER,
+	"UFI": FrameUFID,
+	"ULT": FrameUSLT,
+	"WAF": FrameWOAF,
+	"WAR": FrameWOAR,
+	"WAS": FrameWOAS,
+	"WCM": FrameWCOM,
+	"WCP": FrameWCOP,
+	"WPB": FrameWPUB,
+	"WXX": FrameWXXX,
+
+	// These are not part of the specification, but are
+	// written by iTunes.
+	"TSA": FrameTSOA,
+	"TSP": FrameTSOP,
+	"TST": FrameTSOT,
+}
+
+// frameIDV22 returns the equivalent frame id for the
+// three character v2.2.0 frame id at the start of data.
+// Frame ids without an equivalent are returned with a
+// trailing zero byte.
+func frameIDV22(data []byte) FrameID {
+	_ = data[2]
+
+	if validIDByte(data[0]) && validIDByte(data[1]) && validIDByte(data[2]) {
+		if id, ok := v22FrameIDs[string(data[:3])]; ok {
+			return id
+		}
+
+		return FrameID(data[0])<<24 | FrameID(data[1])<<16 | FrameID(data[2])<<8
+	}
+
+	if data[0] == 0 && data[1] == 0 && data[2] == 0 {
+		// This is probably the beginning of padding.
+		return 0
+	}
+
+	return invalidFrameID
+}
+
+// picToAPIC converts the data of a v2.2.0 PIC frame to
+// the layout of an APIC frame by replacing the three
+// character image format with a MIME type.
+func picToAPIC(data []byte) []byte {
+	// Quoting from §4.15 of id3v2-00.txt:
+	//   Attached picture   "PIC"
+	//   Frame size         $xx xx xx
+	//   Text encoding      $xx
+	//   Image format       $xx xx xx
+	//   Picture type       $xx
+	//   Description        <textstring> $00 (00)
+	//   Picture data       <binary data>
+	if len(data) < 4 {
+		return data
+	}
+
+	var mime string
+	switch format := strings.ToUpper(string(data[1:4])); format {
+	case "JPG":
+		mime = "image/jpeg"
+	case "-->":
+		// A link to the image, this is unchanged in APIC.
+		mime = format
+	default:
+		mime = "image/" + strings.ToLower(format)
+	}
+
+	out := make([]byte, 0, len(data)+len(mime)-2)
+	out = append(out, data[0])
+	out = append(out, mime...)
+	out = append(out, 0x00)
+	return append(out, data[4:]...)
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"testing"
+)
+
+// rawFrameV22 returns a v2.2.0 frame header and data.
+func rawFrameV22(id string, data []byte) []byte {
+	n := len(data)
+	header := []byte{id[0], id[1], id[2], byte(n >> 16), byte(n >> 8), byte(n)}
+	return append(header, data...)
+}
+
+func TestScanV22(t *testing.T) {
+	var body []byte
+	body = append(body, rawFrameV22("TT2", textData("title"))...)
+	body = append(body, rawFrameV22("TP1", textData("artist"))...)
+	body = append(body, rawFrameV22("PIC", []byte("\x00JPG\x03desc\x00\xff\xd8"))...)
+	body = append(body, rawFrameV22("PIC", []byte("\x00-->\x03\x00http://example.com/"))...)
+	body = append(body, rawFrameV22("CRM", []byte("owner\x00"))...)
+	body = append(body, make([]byte, 16)...)
+
+	tags, err := ScanTags(bytes.NewReader(rawTag(Version22, 0, body)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	tag := tags[0]
+	if tag.Version != Version22 || tag.Padding != 16 {
+		t.Errorf("got version %d and padding %d", tag.Version, tag.Padding)
+	}
+
+	if len(tag.Frames) != 5 {
+		t.Fatalf("got %d frames, want 5", len(tag.Frames))
+	}
+
+	checkText(t, tag.Frames, FrameTIT2, "title")
+	checkText(t, tag.Frames, FrameTPE1, "artist")
+
+	for i, want := range []struct {
+		id   FrameID
+		data string
+	}{
+		{FrameAPIC, "\x00image/jpeg\x00\x03desc\x00\xff\xd8"},
+		{FrameAPIC, "\x00-->\x00\x03\x00http://example.com/"},
+		{FrameID('C')<<24 | FrameID('R')<<16 | FrameID('M')<<8, "owner\x00"},
+	} {
+		f := tag.Frames[i+2]
+		if f.ID != want.id || f.Version != Version22 || string(f.Data) != want.data {
+			t.Errorf("frame %d: got %v, want %s with %q", i+2, f, want.id, want.data)
+		}
+	}
+}
+
+func TestScanV22Compression(t *testing.T) {
+	// Compressed v2.2.0 tags are ignored as no compression
+	// scheme was ever defined.
+	data := rawTag(Version22, TagFlagV22Compression, rawFrameV22("TT2", textData("title")))
+
+	tags, err := ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 1 {
+		t.Fatalf("got %d tags, want 1", len(tags))
+	}
+
+	if len(tags[0].Frames) != 0 {
+		t.Errorf("got frames %v, want none", tags[0].Frames)
+	}
+}
+
+func TestFrameIDV22(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		want FrameID
+	}{
+		{"TT2", FrameTIT2},
+		{"COM", FrameCOMM},
+		{"TST", FrameTSOT},
+		{"XYZ", FrameID('X')<<24 | FrameID('Y')<<16 | FrameID('Z')<<8},
+		{"\x00\x00\x00", 0},
+		{"T!2", invalidFrameID},
+	} {
+		if got := frameIDV22([]byte(tc.id)); got != tc.want {
+			t.Errorf("frameIDV22(%q) = %08x, want %08x", tc.id, uint32(got), uint32(tc.want))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// rawFrame returns a v2.3.0 frame header and data with
+// the frame size written as a 32-bit integer.
+func rawFrame(id string, flags FrameFlags, data []byte) []byte {
+	var header [10]byte
+	copy(header[:], id)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	binary.BigEndian.PutUint16(header[8:], uint16(flags))
+	return append(header[:], data...)
+}
+
+// rawTag returns a tag header for body followed by body.
+func rawTag(version Version, flags TagFlags, body []byte) []byte {
+	header := []byte{'I', 'D', '3', byte(version), 0, byte(flags), 0, 0, 0, 0}
+	putSyncsafe(header[6:], uint32(len(body)))
+	return append(header, body...)
+}
+
+func checkText(t *testing.T, frames Frames, id FrameID, want string) {
+	t.Helper()
+
+	f := frames.Lookup(id)
+	if f == nil {
+		t.Fatalf("missing %s frame", id)
+	}
+
+	got, err := f.Text()
+	if err != nil {
+		t.Fatalf("%s: Text failed: %v", id, err)
+	}
+
+	if got != want {
+		t.Errorf("%s: got %q, want %q", id, got, want)
+	}
+}
+
+// textData returns ISO-8859-1 text frame data.
+func textData(s string) []byte {
+	return append([]byte{textEncodingISO88591}, s...)
+}
+
+func TestScanTags(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+	first := encodeTag(t, frames, &EncodeOptions{Padding: 16})
+	second := encodeTag(t, frames, &EncodeOptions{Version: Version23})
+
+	var data []byte
+	data = append(data, first...)
+	data = append(data, "audio"...)
+	data = append(data, second...)
+
+	tags, err := ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("got %d tags, want 2", len(tags))
+	}
+
+	for i, want := range []struct {
+		version      Version
+		offset, size int64
+		padding      int64
+	}{
+		{Version24, 0, int64(len(first)), 16},
+		{Version23, int64(len(first)) + 5, int64(len(second)), 0},
+	} {
+		tag := tags[i]
+		if tag.Version != want.version || tag.Offset != want.offset ||
+			tag.Size != want.size || tag.Padding != want.padding {
+			t.Errorf("tag %d: got version %d, offset %d, size %d and padding %d",
+				i, tag.Version, tag.Offset, tag.Size, tag.Padding)
+		}
+
+		checkText(t, tag.Frames, FrameTIT2, "title")
+	}
+}
+
+func TestScanFileSkipAudio(t *testing.T) {
+	frames := func(title string) Frames {
+		return Frames{{ID: FrameTIT2, Version: Version24, Data: textData(title)}}
+	}
+
+	var data []byte
+	data = append(data, encodeTag(t, frames("prepended"), nil)...)
+	data = append(data, "audio "...)
+	data = append(data, encodeTag(t, frames("audio"), nil)...)
+	data = append(data, encodeTag(t, frames("appended"), &EncodeOptions{Footer: true})...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		skipAudio bool
+		want      []string
+	}{
+		{false, []string{"prepended", "audio", "appended"}},
+		{true, []string{"prepended", "appended"}},
+	} {
+		got, err := (&ScanOptions{SkipAudio: tc.skipAudio}).ScanFile(path)
+		if err != nil {
+			t.Fatalf("ScanFile failed: %v", err)
+		}
+
+		if len(got) != len(tc.want) {
+			t.Fatalf("SkipAudio %t: got %d frames, want %d", tc.skipAudio, len(got), len(tc.want))
+		}
+
+		for i, want := range tc.want {
+			checkText(t, got[i:i+1], FrameTIT2, want)
+		}
+	}
+}
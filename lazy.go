@@ -0,0 +1,60 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io"
+)
+
+// Loaded reports whether the frame data has been read.
+// It is only false for frames read with the Lazy option
+// that have not yet been loaded.
+func (f *Frame) Loaded() bool {
+	return f.r == nil
+}
+
+// Load reads the frame data of a frame read with the
+// Lazy option. It does nothing if the frame data has
+// already been read.
+//
+// The reader the frame was read from must still be
+// valid.
+func (f *Frame) Load() error {
+	if f.r == nil {
+		return nil
+	}
+
+	data, err := readAt(f.r, f.Offset, f.Size)
+	if err != nil {
+		return err
+	}
+
+	if f.Version == Version22 && f.ID == FrameAPIC {
+		data = picToAPIC(data)
+	}
+
+	f.Data, f.r = data, nil
+	return nil
+}
+
+// Open returns a reader of the frame data. If the frame
+// data has not been read, it is read on demand from the
+// reader the frame was read from, without being loaded
+// into memory.
+func (f *Frame) Open() (*io.SectionReader, error) {
+	if f.r != nil && f.Version == Version22 && f.ID == FrameAPIC {
+		// PIC frames have to be converted to APIC.
+		if err := f.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.r != nil {
+		return io.NewSectionReader(f.r, f.Offset, f.Size), nil
+	}
+
+	return io.NewSectionReader(bytes.NewReader(f.Data), 0, int64(len(f.Data))), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+func TestScanTagsAtLazy(t *testing.T) {
+	picture := bytes.Repeat([]byte{0xff, 0xd8}, 1<<10)
+	apic := append([]byte("\x00image/jpeg\x00\x03\x00"), picture...)
+	text := textData(strings.Repeat("compressed ", 20))
+
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+		{ID: FrameAPIC, Version: Version24, Data: apic},
+		{ID: FrameTPE1, Version: Version24,
+			Flags:      FrameFlagV24Compression | FrameFlagV24DataLengthIndicator,
+			DataLength: uint32(len(text)), Data: compress(text)},
+	}, nil)
+	data = append(data, "audio"...)
+
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+	tags, err := (&ScanOptions{Lazy: true}).ScanTagsAt(r, int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	if r.n >= len(picture) {
+		t.Errorf("read %d bytes, want fewer than %d", r.n, len(picture))
+	}
+
+	frames := tags[0].Frames
+	tit2, pic, tpe1 := frames[0], frames[1], frames[2]
+	if tit2.Loaded() || pic.Loaded() || tit2.Data != nil {
+		t.Error("frames without encodings were loaded")
+	}
+
+	// Frames with encodings are always read.
+	if !tpe1.Loaded() || !bytes.Equal(tpe1.Data, text) {
+		t.Errorf("got TPE1 frame %v", tpe1)
+	}
+
+	if !bytes.Equal(data[pic.Offset:pic.Offset+pic.Size], apic) {
+		t.Errorf("got offset %d and size %d", pic.Offset, pic.Size)
+	}
+
+	sr, err := pic.Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if got, err := ioutil.ReadAll(sr); err != nil || !bytes.Equal(got, apic) {
+		t.Errorf("Open returned %d bytes, %v", len(got), err)
+	}
+
+	if pic.Loaded() {
+		t.Error("Open loaded the frame")
+	}
+
+	checkText(t, frames, FrameTIT2, "title")
+	if !tit2.Loaded() {
+		t.Error("Text did not load the frame")
+	}
+
+	if err := pic.Load(); err != nil || !pic.Loaded() || !bytes.Equal(pic.Data, apic) {
+		t.Errorf("Load returned %v and data %d bytes", err, len(pic.Data))
+	}
+
+	if sr, err := pic.Open(); err != nil || sr.Size() != int64(len(apic)) {
+		t.Errorf("Open of loaded frame returned size %d, %v", sr.Size(), err)
+	}
+}
+
+func TestScanTagsAtLazyV22(t *testing.T) {
+	data := rawTag(Version22, 0, rawFrameV22("PIC", []byte("\x00PNG\x03\x00\x89PNG")))
+
+	tags, err := (&ScanOptions{Lazy: true}).ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	pic := tags[0].Frames[0]
+	sr, err := pic.Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	got, err := ioutil.ReadAll(sr)
+	if want := "\x00image/png\x00\x03\x00\x89PNG"; err != nil || string(got) != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestEncodeLazy(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, nil)
+
+	tags, err := (&ScanOptions{Lazy: true}).ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	// Encode loads the frames it writes.
+	if got := encodeTag(t, tags[0].Frames, nil); !bytes.Equal(got, data) {
+		t.Errorf("got %q, want %q", got, data)
+	}
+}
+
+func TestScanFileLazy(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, nil)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	// The file is closed by the time ScanFile returns, so
+	// the frames must already have been loaded.
+	frames, err := (&ScanOptions{Lazy: true, SkipAudio: true}).ScanFile(path)
+	if err != nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+
+	if !frames[0].Loaded() {
+		t.Error("frame was not loaded")
+	}
+
+	checkText(t, frames, FrameTIT2, "title")
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// scanAll reads data with ScanTags, ScanTagsAt and a
+// Reader, returning the error from each.
+func scanAll(o *ScanOptions, data []byte) []error {
+	_, errTags := o.ScanTags(bytes.NewReader(data))
+	_, errAt := o.ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+
+	var errReader error
+	r := o.NewReader(bytes.NewReader(data))
+	for errReader == nil {
+		_, errReader = r.Next()
+	}
+
+	return []error{errTags, errAt, errReader}
+}
+
+func TestScanLimits(t *testing.T) {
+	text := textData(strings.Repeat("a", 1000))
+
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+		{ID: FrameTPE1, Version: Version24,
+			Flags:      FrameFlagV24Compression | FrameFlagV24DataLengthIndicator,
+			DataLength: uint32(len(text)), Data: compress(text)},
+		{ID: FrameTALB, Version: Version24, Data: textData("album")},
+	}, &EncodeOptions{Padding: 16})
+
+	for _, tc := range []struct {
+		name string
+		o    ScanOptions
+		err  error
+	}{
+		{"MaxTagSize", ScanOptions{MaxTagSize: int64(len(data) - 1)}, ErrTagSizeLimit},
+		{"MaxFrameSize", ScanOptions{MaxFrameSize: 10}, ErrFrameSizeLimit},
+		{"MaxFrames", ScanOptions{MaxFrames: 2}, ErrFrameCountLimit},
+		{"MaxDecompressedSize", ScanOptions{MaxDecompressedSize: int64(len(text) - 1)},
+			ErrDecompressedSizeLimit},
+	} {
+		for _, mode := range []ScanMode{ScanDefault, ScanLenient} {
+			tc.o.Mode = mode
+			for i, err := range scanAll(&tc.o, data) {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("%s, mode %d, reader %d: got %v, want %v", tc.name, mode, i, err, tc.err)
+				}
+			}
+		}
+	}
+
+	o := &ScanOptions{
+		MaxTagSize:          int64(len(data)),
+		MaxFrameSize:        int64(len(compress(text)) + 4),
+		MaxFrames:           3,
+		MaxDecompressedSize: int64(len(text)),
+	}
+	for i, err := range scanAll(o, data) {
+		if err != nil && err != io.EOF {
+			t.Errorf("reader %d: got %v at the limits", i, err)
+		}
+	}
+}
+
+func TestScanTagSizeLimitBeforeRead(t *testing.T) {
+	// The tag claims to be far larger than the data, so it
+	// must be rejected from its header alone.
+	header := []byte{'I', 'D', '3', byte(Version24), 0, 0, 0x7f, 0x7f, 0x7f, 0x7f}
+
+	o := &ScanOptions{MaxTagSize: 1 << 20}
+	for i, err := range scanAll(o, header) {
+		var te *TagError
+		if !errors.As(err, &te) || te.Err != ErrTagSizeLimit || te.Offset != 0 {
+			t.Errorf("reader %d: got %v, want ErrTagSizeLimit", i, err)
+		}
+	}
+}
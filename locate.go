@@ -0,0 +1,195 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io"
+)
+
+// ScanTagsAt reads the ID3v2 tags at the locations
+// defined by the specification, where size is the length
+// of r, and returns them in order. Unlike ScanTags, it
+// does not read the audio between the tags.
+//
+// A tag is looked for at the start of r, and any SEEK
+// frames are followed to the tags they point to. Tags
+// that directly follow one of these tags are also read.
+// An appended tag is found by its footer, either at the
+// end of r or before any trailers found by FindTrailers.
+//
+// ScanTagsAt uses the default options. It is equivalent
+// to calling ScanTagsAt on a zero ScanOptions.
+func ScanTagsAt(r io.ReaderAt, size int64) ([]*Tag, error) {
+	return new(ScanOptions).ScanTagsAt(r, size)
+}
+
+// ScanTagsAt is like the package level ScanTagsAt but
+// uses the options in o.
+func (o *ScanOptions) ScanTagsAt(r io.ReaderAt, size int64) ([]*Tag, error) {
+	var tags []*Tag
+
+	// seen holds the offsets of the tags already read, so
+	// that SEEK frames pointing at a tag that was already
+	// read are not followed again.
+	seen := make(map[int64]bool)
+
+	for offset := int64(0); offset != -1 && !seen[offset]; {
+		tag, next, err := o.readTagAt(r, len(tags), offset, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if tag == nil {
+			break
+		}
+
+		seen[offset] = true
+		tags = append(tags, tag)
+
+		offset = next
+		if offset == -1 {
+			// Without a SEEK frame, another tag may directly
+			// follow this one, such as when a tag has been
+			// prepended to a file that already had one.
+			offset = tag.Offset + tag.Size
+		}
+
+		if offset+10 > size {
+			// There is no room for a tag before the end of r.
+			offset = -1
+		}
+	}
+
+	trailers, err := FindTrailers(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quoting from §3.4 of id3v2.4.0-structure.txt:
+	//   To speed up the process of locating an ID3v2 tag
+	//   when searching from the end of a file, a footer can
+	//   be added to the tag. It is REQUIRED to add a footer
+	//   to an appended tag, i.e. a tag located after all
+	//   tagged audio.
+	ends := []int64{size}
+	for i := len(trailers) - 1; i >= 0; i-- {
+		ends = append(ends, trailers[i].Offset)
+	}
+
+	for _, end := range ends {
+		offset, err := appendedTag(r, end)
+		if err != nil {
+			return nil, err
+		}
+
+		if offset == -1 {
+			continue
+		}
+
+		if seen[offset] {
+			break
+		}
+
+		if len(tags) != 0 {
+			last := tags[len(tags)-1]
+			if offset < last.Offset+last.Size {
+				continue
+			}
+		}
+
+		appended, _, err := o.readTagAt(r, len(tags), offset, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if appended != nil {
+			tags = append(tags, appended)
+		}
+
+		break
+	}
+
+	return tags, nil
+}
+
+// appendedTag returns the offset of the tag whose footer
+// ends at end, or -1 if there is no such footer.
+func appendedTag(r io.ReaderAt, end int64) (int64, error) {
+	if end < 10 {
+		return -1, nil
+	}
+
+	var footer [10]byte
+	if _, err := r.ReadAt(footer[:], end-10); err != nil {
+		return -1, err
+	}
+
+	if string(footer[:3]) != "3DI" ||
+		TagFlags(footer[5])&TagFlagFooter != TagFlagFooter {
+		return -1, nil
+	}
+
+	// The footer is a copy of the header, but with a
+	// different identifier.
+	copy(footer[:], id3Token)
+
+	n := tagSize(footer[:])
+	if n == -1 || int64(n) > end {
+		return -1, nil
+	}
+
+	return end - int64(n), nil
+}
+
+// readTagAt reads and parses the tag at offset, where
+// size is the length of r and index is the index of the
+// tag amongst the tags read. It returns nil if there is no
+// tag at offset. It also returns the offset of the next
+// tag given by a SEEK frame, or -1.
+func (o *ScanOptions) readTagAt(r io.ReaderAt, index int, offset, size int64) (*Tag, int64, error) {
+	var header [10]byte
+	if _, err := r.ReadAt(header[:], offset); err == io.EOF {
+		return nil, -1, nil
+	} else if err != nil {
+		return nil, -1, err
+	}
+
+	n := int64(tagSize(header[:]))
+	if n == -1 {
+		return nil, -1, nil
+	}
+
+	if err := o.checkTagSize(index, offset, n); err != nil {
+		return nil, -1, err
+	}
+
+	avail := n
+	if n > size-offset {
+		if o.Mode != ScanLenient {
+			return nil, -1, &TagError{
+				Offset:   offset,
+				TagIndex: index,
+				Err:      ErrTruncatedTag,
+			}
+		}
+
+		// In lenient mode, as many frames as possible are
+		// read from a truncated tag. They are not read lazily
+		// as they may extend past the end of r.
+		avail = size - offset
+	}
+
+	if o.Lazy && avail == n {
+		return o.readTag(header[:], io.NewSectionReader(r, offset+10, n-10), index, offset, n, r)
+	}
+
+	data, err := readAt(r, offset+10, avail-10)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return o.readTag(header[:], bytes.NewReader(data), index, offset, n, nil)
+}
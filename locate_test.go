@@ -0,0 +1,77 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScanTagsAt(t *testing.T) {
+	frames := func(title string) Frames {
+		return Frames{{ID: FrameTIT2, Version: Version24, Data: textData(title)}}
+	}
+
+	prepended := encodeTag(t, frames("prepended"), &EncodeOptions{Padding: 16})
+	appended := encodeTag(t, frames("appended"), &EncodeOptions{Footer: true})
+
+	// The audio contains a valid looking tag which must not
+	// be found as it is not at a location defined by the
+	// specification.
+	audio := append([]byte("audio "), encodeTag(t, frames("audio"), nil)...)
+
+	v1 := rawV1("Title", "", "", "", "", 0, 0)
+	ape := rawAPE(false)
+
+	for _, tc := range []struct {
+		name  string
+		parts [][]byte
+		want  []string
+	}{
+		{"none", [][]byte{audio}, nil},
+		{"prepended", [][]byte{prepended, audio}, []string{"prepended"}},
+		{"consecutive", [][]byte{prepended, prepended, audio}, []string{"prepended", "prepended"}},
+		{"appended", [][]byte{audio, appended}, []string{"appended"}},
+		{"both", [][]byte{prepended, audio, appended}, []string{"prepended", "appended"}},
+		{"before trailers", [][]byte{prepended, audio, appended, ape, v1},
+			[]string{"prepended", "appended"}},
+		{"only tag", [][]byte{appended}, []string{"appended"}},
+	} {
+		data := bytes.Join(tc.parts, nil)
+
+		tags, err := ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: ScanTagsAt failed: %v", tc.name, err)
+		}
+
+		if len(tags) != len(tc.want) {
+			t.Fatalf("%s: got %d tags, want %d", tc.name, len(tags), len(tc.want))
+		}
+
+		for i, tag := range tags {
+			checkText(t, tag.Frames, FrameTIT2, tc.want[i])
+
+			raw := prepended
+			if tc.want[i] == "appended" {
+				raw = appended
+			}
+
+			if !bytes.Equal(data[tag.Offset:tag.Offset+tag.Size], raw) {
+				t.Errorf("%s: tag %d has offset %d and size %d", tc.name, i, tag.Offset, tag.Size)
+			}
+		}
+	}
+}
+
+func TestScanTagsAtTruncated(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, nil)
+	data = data[:len(data)-1]
+
+	if _, err := ScanTagsAt(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("ScanTagsAt succeeded with a truncated tag")
+	}
+}
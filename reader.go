@@ -0,0 +1,152 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Reader reads the frames of the ID3v2 tags in a stream
+// one at a time. Only a single frame is held in memory
+// at once.
+type Reader struct {
+	o  *ScanOptions
+	br *bufio.Reader
+
+	// offset is the position of br relative to the start
+	// of the stream.
+	offset int64
+
+	tr   *tagReader
+	tag  *Tag
+	tags int
+	err  error
+}
+
+// NewReader returns a Reader that reads the frames of
+// the ID3v2 tags in r. Tags are detected in the same way
+// as Scan.
+//
+// NewReader uses the default options. It is equivalent
+// to calling NewReader on a zero ScanOptions.
+func NewReader(r io.Reader) *Reader {
+	return new(ScanOptions).NewReader(r)
+}
+
+// NewReader is like the package level NewReader but uses
+// the options in o.
+func (o *ScanOptions) NewReader(r io.Reader) *Reader {
+	return &Reader{
+		o:  o,
+		br: bufio.NewReaderSize(r, 64<<10),
+	}
+}
+
+// Next returns the next frame. It returns io.EOF once
+// every tag has been read.
+//
+// Unlike Scan, an encrypted frame is only decrypted if
+// its ENCR frame precedes it in the tag.
+func (r *Reader) Next() (*Frame, error) {
+	for r.err == nil {
+		if r.tr == nil {
+			r.err = r.nextTag()
+			continue
+		}
+
+		frame, err := r.tr.next()
+		if err == nil {
+			return frame, nil
+		} else if err != io.EOF {
+			r.err = err
+			break
+		}
+
+		r.offset = r.tag.Offset + r.tag.Size
+
+		if next := r.tr.nextTag(); next != -1 {
+			// Skip over the audio between the tag and the tag
+			// its SEEK frame points to.
+			n, err := r.br.Discard(int(next - r.offset))
+			r.offset += int64(n)
+
+			if err != nil {
+				r.err = err
+				break
+			}
+		}
+
+		r.tr = nil
+	}
+
+	return nil, r.err
+}
+
+// Tag returns the tag that the frame last returned by
+// Next belongs to. The Frames field of the tag is not
+// populated.
+func (r *Reader) Tag() *Tag {
+	return r.tag
+}
+
+// nextTag finds the next tag in the stream and begins
+// reading it.
+func (r *Reader) nextTag() error {
+	for {
+		data, err := r.br.Peek(r.br.Size())
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return err
+		}
+
+		i := bytes.Index(data, id3Token)
+		switch {
+		case i == -1 && err == io.EOF:
+			return io.EOF
+		case i == -1:
+			// The last two bytes may be the start of a tag.
+			i = len(data) - 2
+		case i != 0:
+			// Skip to the start of what may be a tag.
+		case len(data) < 10 && r.o.Mode == ScanLenient:
+			// A partial tag header cannot be told apart from
+			// audio.
+			return io.EOF
+		case len(data) < 10:
+			return &TagError{
+				Offset:   r.offset,
+				TagIndex: r.tags,
+				Err:      ErrTruncatedTag,
+			}
+		default:
+			size := tagSize(data)
+			if size == -1 {
+				i = 3
+				break
+			}
+
+			if err := r.o.checkTagSize(r.tags, r.offset, int64(size)); err != nil {
+				return err
+			}
+
+			header := append([]byte(nil), data[:10]...)
+			r.br.Discard(10)
+
+			lr := io.LimitReader(r.br, int64(size)-10)
+			tr, err := r.o.newTagReader(header, lr, r.tags, r.offset, int64(size), nil)
+			if err != nil {
+				return err
+			}
+
+			r.tr, r.tag = tr, tr.tag
+			r.tags++
+			return nil
+		}
+
+		r.br.Discard(i)
+		r.offset += int64(i)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	text := textData(strings.Repeat("compressed ", 20))
+
+	var data []byte
+	data = append(data, encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("first")},
+		{ID: FrameTPE1, Version: Version24,
+			Flags:      FrameFlagV24Compression | FrameFlagV24DataLengthIndicator,
+			DataLength: uint32(len(text)), Data: compress(text)},
+	}, &EncodeOptions{Padding: 32})...)
+
+	// The audio is larger than the buffer of the Reader.
+	data = append(data, bytes.Repeat([]byte("audio "), 20<<10)...)
+	data = append(data, encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("second")},
+	}, &EncodeOptions{Version: Version23})...)
+	data = append(data, rawTag(Version22, 0, rawFrameV22("TT2", textData("third")))...)
+	data = append(data, "audio"...)
+
+	tags, err := ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	r := NewReader(bytes.NewReader(data))
+
+	var read []*Tag
+	for i, tag := range tags {
+		for j, want := range tag.Frames {
+			got, err := r.Next()
+			if err != nil {
+				t.Fatalf("tag %d, frame %d: Next failed: %v", i, j, err)
+			}
+
+			if got.ID != want.ID || got.Version != want.Version || got.Flags != want.Flags ||
+				got.Offset != want.Offset || !bytes.Equal(got.Data, want.Data) {
+				t.Errorf("tag %d, frame %d: got %v, want %v", i, j, got, want)
+			}
+
+			if rt := r.Tag(); rt.Offset != tag.Offset || rt.Size != tag.Size ||
+				rt.Version != tag.Version {
+				t.Errorf("tag %d: got tag at offset %d with size %d", i, rt.Offset, rt.Size)
+			}
+		}
+
+		read = append(read, r.Tag())
+	}
+
+	if f, err := r.Next(); err != io.EOF {
+		t.Errorf("got %v, %v, want io.EOF", f, err)
+	}
+
+	// The padding is only known once every frame of a tag
+	// has been read.
+	for i, tag := range read {
+		if tag.Padding != tags[i].Padding {
+			t.Errorf("tag %d: got padding %d, want %d", i, tag.Padding, tags[i].Padding)
+		}
+	}
+
+	// Once Next has failed, it keeps failing.
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestReaderSEEK(t *testing.T) {
+	data, first, audio := seekTags(t)
+
+	r := NewReader(bytes.NewReader(data))
+
+	var titles []string
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+
+		if f.ID == FrameTIT2 {
+			text, _ := f.Text()
+			titles = append(titles, text)
+		}
+	}
+
+	if len(titles) != 2 || titles[1] != "second" {
+		t.Errorf("got titles %q", titles)
+	}
+
+	if r.Tag().Offset != int64(first+audio) {
+		t.Errorf("got last tag at offset %d, want %d", r.Tag().Offset, first+audio)
+	}
+}
+
+func TestReaderDecrypt(t *testing.T) {
+	reg := &EncryptionRegistration{Owner: "toy", Data: []byte{0x55}}
+	encrypted, _ := xorCipher(reg, textData("title"))
+
+	data := encodeTag(t, Frames{
+		{ID: FrameENCR, Version: Version24, Data: []byte("toy\x00\x80\x55")},
+		{ID: FrameTIT2, Version: Version24, Flags: FrameFlagV24Encryption,
+			EncryptionMethod: 0x80, Data: encrypted},
+	}, nil)
+
+	o := &ScanOptions{Decrypters: map[string]Decrypter{
+		"toy": DecrypterFunc(xorCipher),
+	}}
+	r := o.NewReader(bytes.NewReader(data))
+
+	if f, err := r.Next(); err != nil || f.ID != FrameENCR {
+		t.Fatalf("got %v, %v, want ENCR frame", f, err)
+	}
+
+	f, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+
+	checkText(t, Frames{f}, FrameTIT2, "title")
+}
@@ -0,0 +1,33 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// SeekOffset interprets the frame data as the minimum
+// offset from the end of the tag to the beginning of the
+// next tag, according to §4.29 of id3v2.4.0-frames.txt.
+func (f *Frame) SeekOffset() (uint32, error) {
+	if f.ID != FrameSEEK {
+		return 0, errors.New("id3: frame is not a SEEK frame")
+	}
+
+	if err := f.Load(); err != nil {
+		return 0, err
+	}
+
+	if f.encoded() {
+		return 0, errors.New("id3: encoding frame flags are not supported")
+	}
+
+	if len(f.Data) != 4 {
+		return 0, ErrInvalidFrameData
+	}
+
+	return binary.BigEndian.Uint32(f.Data), nil
+}
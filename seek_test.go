@@ -0,0 +1,102 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"testing/iotest"
+)
+
+// seekTags returns a tag with a SEEK frame pointing over
+// audio to a second tag, followed by audio and the
+// second tag. The audio contains a valid looking tag.
+func seekTags(t *testing.T) (data []byte, first, audio int) {
+	t.Helper()
+
+	skipped := append([]byte("audio "), encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("audio")},
+	}, nil)...)
+
+	seek := make([]byte, 4)
+	binary.BigEndian.PutUint32(seek, uint32(len(skipped)))
+
+	tag := rawTag(Version24, 0, append(rawFrame("TIT2", 0, textData("first")),
+		rawFrame("SEEK", 0, seek)...))
+
+	data = append(data, tag...)
+	data = append(data, skipped...)
+	data = append(data, rawTag(Version24, 0, rawFrame("TIT2", 0, textData("second")))...)
+	return data, len(tag), len(skipped)
+}
+
+func TestScanTagsSEEK(t *testing.T) {
+	data, first, audio := seekTags(t)
+
+	tags, err := ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 2 || tags[1].Offset != int64(first+audio) {
+		t.Fatalf("got %d tags, want 2", len(tags))
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, "first")
+	checkText(t, tags[1].Frames, FrameTIT2, "second")
+}
+
+func TestScanTagsAtSEEK(t *testing.T) {
+	data, first, audio := seekTags(t)
+
+	tags, err := ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	if len(tags) != 2 || tags[1].Offset != int64(first+audio) {
+		t.Fatalf("got %d tags, want 2", len(tags))
+	}
+
+	checkText(t, tags[1].Frames, FrameTIT2, "second")
+}
+
+func TestScanTagsAtSEEKLoop(t *testing.T) {
+	// A SEEK frame pointing back at the tag itself must
+	// not be followed forever.
+	seek := make([]byte, 4)
+	tag := rawTag(Version24, 0, append(rawFrame("TIT2", 0, textData("title")),
+		rawFrame("SEEK", 0, seek)...))
+	data := append(append([]byte(nil), tag...), tag...)
+
+	tags, err := ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	if len(tags) != 2 {
+		t.Errorf("got %d tags, want 2", len(tags))
+	}
+}
+
+func TestScanTagsInvalidHeaderAtEOF(t *testing.T) {
+	// An invalid tag header in the final read must not stop
+	// the search for tags that follow it.
+	data := append([]byte("audio ID3\xff"), encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, nil)...)
+
+	tags, err := ScanTags(iotest.DataErrReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 1 {
+		t.Fatalf("got %d tags, want 1", len(tags))
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, "title")
+}
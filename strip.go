@@ -0,0 +1,154 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type stripReader struct {
+	s   *bufio.Scanner
+	buf []byte
+}
+
+// Strip returns a reader that reads from r with every
+// ID3v2 tag removed. Tags are detected in the same way
+// as Scan, by searching the whole stream for tag
+// headers, so audio that happens to contain a valid
+// looking tag header may also be removed. StripFile
+// does not have this problem.
+func Strip(r io.Reader) io.Reader {
+	return newStripReader(r)
+}
+
+func newStripReader(r io.Reader) *stripReader {
+	sr := new(stripReader)
+	sr.s = bufio.NewScanner(r)
+	sr.s.Buffer(nil, maxTokenSize)
+	sr.s.Split(sr.split)
+	return sr
+}
+
+func (sr *stripReader) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// Tags at the start of data are skipped here rather than
+	// returning without a token, as bufio.Scanner stops at
+	// EOF if no token is returned.
+	var skipped int
+	for {
+		advance, token, err = id3Split(data[skipped:], atEOF)
+		if token == nil || advance != len(token) {
+			break
+		}
+
+		skipped += advance
+	}
+
+	data = data[skipped:]
+
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		// A truncated tag at the end of the stream is left
+		// untouched, it may very well be audio.
+		return skipped + len(data), data, nil
+	case err != nil:
+		return 0, nil, err
+	case token != nil:
+		// Return the audio that precedes the tag, the tag
+		// will be skipped on the next call.
+		i := advance - len(token)
+		return skipped + i, data[:i], nil
+	case advance != 0:
+		return skipped + advance, data[:advance], nil
+	case atEOF && len(data) != 0:
+		return skipped + len(data), data, nil
+	default:
+		return skipped, nil, nil
+	}
+}
+
+func (sr *stripReader) Read(p []byte) (int, error) {
+	for len(sr.buf) == 0 {
+		if !sr.s.Scan() {
+			if err := sr.s.Err(); err != nil {
+				return 0, err
+			}
+
+			return 0, io.EOF
+		}
+
+		sr.buf = sr.s.Bytes()
+	}
+
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+// StripFile removes the ID3v2 tags from a file. The
+// file is rewritten in the same manner as UpdateFile.
+//
+// Only the tags found by ScanTagsAt are removed, the
+// rest of the file, including any trailers found by
+// FindTrailers such as ID3v1 or APEv2 tags, is copied
+// unchanged.
+//
+// StripFile uses the default options. It is equivalent
+// to calling StripFile on a zero UpdateOptions.
+func StripFile(path string) error {
+	return new(UpdateOptions).StripFile(path)
+}
+
+// StripFile is like the package level StripFile but
+// uses the options in o.
+func (o *UpdateOptions) StripFile(path string) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The frames are never read, so problems with them
+	// should not prevent the tags from being removed.
+	so := &ScanOptions{
+		Mode: ScanLenient,
+		Lazy: true,
+	}
+
+	tags, err := so.ScanTagsAt(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return o.rewriteFile(path, fi, func(w io.Writer) error {
+		var pos int64
+		for _, tag := range tags {
+			if _, err := io.Copy(w, io.NewSectionReader(f, pos, tag.Offset-pos)); err != nil {
+				return err
+			}
+
+			pos = tag.Offset + tag.Size
+		}
+
+		_, err := io.Copy(w, io.NewSectionReader(f, pos, fi.Size()-pos))
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestStrip(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+	tag := encodeTag(t, frames, &EncodeOptions{Padding: 16})
+
+	for _, tc := range []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"leading", append(append([]byte(nil), tag...), "audio"...), "audio"},
+		{"consecutive", append(append(append([]byte(nil), tag...), tag...), "audio"...), "audio"},
+		{"trailing", append([]byte("audio"), append(tag, tag...)...), "audio"},
+		{"truncated", append([]byte("audio"), tag[:20]...), "audio" + string(tag[:20])},
+		{"none", []byte("audio"), "audio"},
+	} {
+		got, err := ioutil.ReadAll(Strip(bytes.NewReader(tc.in)))
+		if err != nil {
+			t.Fatalf("%s: ReadAll failed: %v", tc.name, err)
+		}
+
+		if string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStripFile(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+
+	data := encodeTag(t, frames, &EncodeOptions{Padding: 16})
+	data = append(data, "audio"...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	if err := StripFile(path); err != nil {
+		t.Fatalf("StripFile failed: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "audio" {
+		t.Errorf("got %q, want %q", got, "audio")
+	}
+}
+
+func TestStripFileLocations(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+
+	// The audio contains a valid looking tag which must not
+	// be removed as it is not at a location defined by the
+	// specification.
+	audio := append([]byte("audio "), encodeTag(t, frames, nil)...)
+	audio = append(audio, " more audio"...)
+
+	v1 := rawV1("Title", "", "", "", "", 0, 0)
+
+	var data []byte
+	data = append(data, encodeTag(t, frames, &EncodeOptions{Padding: 16})...)
+	data = append(data, audio...)
+	data = append(data, encodeTag(t, frames, &EncodeOptions{Footer: true})...)
+	data = append(data, v1...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	if err := StripFile(path); err != nil {
+		t.Fatalf("StripFile failed: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := append(append([]byte(nil), audio...), v1...); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStripFileConsecutive(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+	tag := encodeTag(t, frames, &EncodeOptions{Padding: 16})
+
+	var data []byte
+	data = append(data, tag...)
+	data = append(data, encodeTag(t, frames, &EncodeOptions{Version: Version23})...)
+	data = append(data, "audio"...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	if err := StripFile(path); err != nil {
+		t.Fatalf("StripFile failed: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "audio" {
+		t.Errorf("got %q, want %q", got, "audio")
+	}
+}
@@ -0,0 +1,776 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// tagReader reads the frames of a single tag in order,
+// reading the tag data from r only once.
+type tagReader struct {
+	o   *ScanOptions
+	tag *Tag
+
+	// index is the index of the tag amongst the tags read.
+	index int
+
+	header []byte
+
+	// r is the tag data that follows the header. base is
+	// the offset of the first byte of r, pos is the offset
+	// of the next byte of r and end is the offset of the
+	// footer, or the end of the tag.
+	r              io.Reader
+	base, pos, end int64
+
+	// ahead holds the data that has been peeked at but not
+	// yet read, if r is not an io.ReaderAt.
+	ahead []byte
+
+	// removed holds, for a resynchronised tag, the offset
+	// relative to base of each byte of r that followed a
+	// byte removed by resynchronisation.
+	removed []int64
+
+	// lazy is the reader frames are lazily read from, or
+	// nil if frames are read immediately.
+	lazy io.ReaderAt
+
+	// crc is the CRC-32 of the tag data read so far, or nil
+	// if the tag does not have a CRC to verify.
+	crc hash.Hash32
+
+	regs map[byte]*EncryptionRegistration
+
+	seek    uint32
+	hasSeek bool
+
+	// frames is the number of frames read so far.
+	frames int
+
+	done bool
+}
+
+// newTagReader reads the beginning of the tag of size
+// bytes at offset, where header is the tag header, r
+// reads the remaining tag data and index is the index of
+// the tag amongst the tags read. If lazy is non-nil, the
+// data of frames without any frame-level encodings is
+// left to be read from lazy by Frame.Load.
+func (o *ScanOptions) newTagReader(header []byte, r io.Reader, index int, offset, size int64, lazy io.ReaderAt) (*tagReader, error) {
+	if string(header[:3]) != "ID3" {
+		panic("id3: invalid tag header")
+	}
+
+	tr := &tagReader{
+		o: o,
+		tag: &Tag{
+			Version:  Version(header[3]),
+			Revision: header[4],
+			Flags:    TagFlags(header[5]),
+			Offset:   offset,
+			Size:     size,
+		},
+
+		index: index,
+
+		header: header,
+
+		r:    r,
+		base: offset + 10,
+		pos:  offset + 10,
+		end:  offset + size,
+
+		lazy: lazy,
+	}
+
+	switch tr.tag.Version {
+	case Version24, Version23, Version22:
+	default:
+		panic("id3: invalid tag header")
+	}
+
+	if err := tr.begin(); err != nil && err != io.EOF && !tr.truncated(err) {
+		return nil, err
+	}
+
+	return tr, nil
+}
+
+// begin reads the extended header, if any, and prepares
+// to read the frames. It returns io.EOF if no frames can
+// be read from the tag.
+func (tr *tagReader) begin() error {
+	version, flags := tr.tag.Version, tr.tag.Flags
+
+	if version == Version22 && flags&TagFlagV22Compression == TagFlagV22Compression {
+		// Quoting from §3.1 of id3v2-00.txt:
+		//   Since no compression scheme has been decided yet,
+		//   the ID3 decoder (for now) should just ignore the
+		//   entire tag if the compression bit is set.
+		if err := tr.skip(tr.end - tr.pos); err != nil {
+			return err
+		}
+
+		tr.done = true
+		return nil
+	}
+
+	if flags&TagFlagFooter == TagFlagFooter {
+		tr.end -= 10
+	}
+
+	if version != Version24 && flags&TagFlagUnsynchronisation == TagFlagUnsynchronisation {
+		// Before v2.4.0, unsynchronisation is applied to the
+		// whole tag, including the extended header and the
+		// frame headers, so the tag is resynchronised before
+		// it is parsed. Positions within the tag are then
+		// relative to the resynchronised data, except in
+		// errors and warnings.
+		start := tr.pos
+
+		data, err := tr.read(tr.end - tr.pos)
+		var missing int64
+		if err != nil {
+			if tr.o.Mode != ScanLenient || !errors.Is(err, ErrTruncatedTag) {
+				return err
+			}
+
+			// In lenient mode, the frames that were read are
+			// still parsed. The truncation is recorded once
+			// the frames reach the end of the data.
+			missing = tr.end - tr.pos - int64(len(data))
+		}
+
+		for i := 0; i+1 < len(data); i++ {
+			if data[i] == 0xff && data[i+1] == 0x00 {
+				tr.removed = append(tr.removed, int64(i+1-len(tr.removed)))
+				i++
+			}
+		}
+
+		data = resynchronise(data)
+		tr.r, tr.base, tr.pos, tr.end = bytes.NewReader(data), start, start, start+int64(len(data))+missing
+	}
+
+	if version == Version22 || flags&TagFlagExtendedHeader == 0 {
+		return nil
+	}
+
+	ehOffset := tr.pos
+	if tr.end-tr.pos < 4 {
+		return tr.abandonTag(ehOffset, ErrInvalidExtendedHeader)
+	}
+
+	data, err := tr.read(4)
+	if err != nil {
+		return err
+	}
+
+	var ehSize int64
+	switch version {
+	case Version24:
+		size := syncsafe(data)
+		if size == syncsafeInvalid {
+			return tr.abandonTag(ehOffset, ErrInvalidExtendedHeader)
+		}
+
+		ehSize = int64(size)
+	case Version23:
+		ehSize = int64(binary.BigEndian.Uint32(data)) + 4
+	default:
+		panic("unhandled version")
+	}
+
+	if ehSize < 4 || tr.end-tr.pos < ehSize-4 {
+		return tr.abandonTag(ehOffset, ErrInvalidExtendedHeader)
+	}
+
+	rest, err := tr.read(ehSize - 4)
+	if err != nil {
+		return err
+	}
+
+	extendedHeader, err := parseExtendedHeader(append(data, rest...), version)
+	if err != nil {
+		return tr.abandonTag(ehOffset, err)
+	}
+
+	tr.tag.ExtendedHeader = extendedHeader
+
+	// The CRC is not verified for lazily read tags as that
+	// would require reading all of the frame data.
+	if extendedHeader.HasCRC && tr.lazy == nil {
+		tr.crc = crc32.NewIEEE()
+	}
+
+	return nil
+}
+
+// read reads the next n bytes of the tag data. If the
+// tag is truncated, the data that could be read is
+// returned along with the error.
+func (tr *tagReader) read(n int64) ([]byte, error) {
+	data := make([]byte, n)
+	m := copy(data, tr.ahead)
+	tr.ahead = tr.ahead[m:]
+
+	if k, err := io.ReadFull(tr.r, data[m:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = tr.tagError(tr.tag.Offset, ErrTruncatedTag)
+		}
+
+		return data[:m+k], err
+	}
+
+	tr.pos += n
+	return data, nil
+}
+
+// peek returns the n bytes of tag data at offset off,
+// which must not precede pos, without reading them.
+func (tr *tagReader) peek(off, n int64) ([]byte, error) {
+	if ra, ok := tr.r.(io.ReaderAt); ok {
+		return readAt(ra, off-tr.base, n)
+	}
+
+	if need := off + n - tr.pos - int64(len(tr.ahead)); need > 0 {
+		data := make([]byte, need)
+		if _, err := io.ReadFull(tr.r, data); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = tr.tagError(tr.tag.Offset, ErrTruncatedTag)
+			}
+
+			return nil, err
+		}
+
+		tr.ahead = append(tr.ahead, data...)
+	}
+
+	return tr.ahead[off-tr.pos : off-tr.pos+n], nil
+}
+
+// skip skips the next n bytes of the tag data.
+func (tr *tagReader) skip(n int64) error {
+	if m := int64(len(tr.ahead)); m != 0 {
+		if m > n {
+			m = n
+		}
+
+		tr.ahead = tr.ahead[m:]
+		tr.pos += m
+		n -= m
+	}
+
+	if s, ok := tr.r.(io.Seeker); ok {
+		if _, err := s.Seek(n, io.SeekCurrent); err != nil {
+			return err
+		}
+	} else if _, err := io.CopyN(ioutil.Discard, tr.r, n); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = tr.tagError(tr.tag.Offset, ErrTruncatedTag)
+		}
+
+		return err
+	}
+
+	tr.pos += n
+	return nil
+}
+
+// hashFrame adds the frame header and data to the CRC-32
+// of the tag.
+func (tr *tagReader) hashFrame(header, data []byte) {
+	if tr.crc == nil {
+		return
+	}
+
+	// Quoting from §3.2 of id3v2.3.0.txt:
+	//   The CRC should be calculated before
+	//   unsynchronisation on the data between the extended
+	//   header and the padding, i.e. the frames and only
+	//   the frames.
+	//
+	// This is satisfied as v2.3.0 tags have already been
+	// resynchronised.
+	tr.crc.Write(header)
+	tr.crc.Write(data)
+}
+
+// next returns the next frame of the tag, or io.EOF once
+// all the frames and the padding have been read.
+func (tr *tagReader) next() (*Frame, error) {
+	for {
+		frame, err := tr.nextFrame()
+		if err != nil && err != io.EOF && tr.truncated(err) {
+			return nil, io.EOF
+		}
+
+		if frame != nil || err != nil {
+			return frame, err
+		}
+	}
+}
+
+// nextFrame is like next, but returns a nil frame and a
+// nil error if the frame was skipped in lenient mode.
+func (tr *tagReader) nextFrame() (*Frame, error) {
+	if tr.done {
+		return nil, io.EOF
+	}
+
+	version, flags := tr.tag.Version, tr.tag.Flags
+
+	headerSize := int64(10)
+	if version == Version22 {
+		headerSize = 6
+	}
+
+	if tr.end-tr.pos <= headerSize {
+		return nil, tr.finish(nil)
+	}
+
+	header, err := tr.read(headerSize)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := &Frame{Version: version}
+
+	if version == Version22 {
+		frame.ID = frameIDV22(header)
+	} else {
+		frame.ID = frameID(header)
+		frame.Flags = FrameFlags(binary.BigEndian.Uint16(header[8:]))
+	}
+
+	switch frame.ID {
+	case 0:
+		// We've probably hit padding, the padding validity
+		// check will handle this.
+		return nil, tr.finish(header)
+	case invalidFrameID:
+		return nil, tr.abandon(tr.pos-headerSize, 0, ErrInvalidFrameID)
+	}
+
+	var size uint32
+	switch version {
+	case Version24:
+		size = syncsafe(header[4:])
+
+		if tr.o.Mode == ScanLenient {
+			// Some software incorrectly writes v2.4.0 frame
+			// sizes as plain 32-bit integers, as in v2.3.0.
+			// The big-endian size is used if only it leads to
+			// another frame, padding or the end of the tag.
+			beSize := binary.BigEndian.Uint32(header[4:])
+			if beSize != size && !tr.landsOnFrame(size) && tr.landsOnFrame(beSize) {
+				size = beSize
+				tr.warn(tr.pos-headerSize, frame.ID, ErrFrameSizeNotSyncsafe)
+			}
+		}
+
+		if size == syncsafeInvalid {
+			return nil, tr.abandon(tr.pos-headerSize, frame.ID, ErrInvalidFrameSize)
+		}
+	case Version23:
+		size = binary.BigEndian.Uint32(header[4:])
+	case Version22:
+		size = uint32(header[3])<<16 | uint32(header[4])<<8 | uint32(header[5])
+	default:
+		panic("unhandled version")
+	}
+
+	if tr.end-tr.pos < int64(size) {
+		return nil, tr.abandon(tr.pos-headerSize, frame.ID, ErrFrameTooLarge)
+	}
+
+	if tr.o.MaxFrameSize > 0 && int64(size) > tr.o.MaxFrameSize {
+		return nil, tr.frameError(tr.pos-headerSize, frame.ID, ErrFrameSizeLimit)
+	}
+
+	if tr.frames++; tr.o.MaxFrames > 0 && tr.frames > tr.o.MaxFrames {
+		return nil, tr.tagError(tr.pos-headerSize, ErrFrameCountLimit)
+	}
+
+	frame.Offset = tr.pos
+	frame.Size = int64(size)
+
+	// ENCR frames are always read so that the frames
+	// that follow them can be decrypted.
+	if tr.lazy != nil && flags&TagFlagUnsynchronisation == 0 &&
+		frame.Flags&encodingFrameFlags == 0 &&
+		(frame.ID != FrameENCR || len(tr.o.Decrypters) == 0) {
+		frame.r = tr.lazy
+
+		if err := tr.skip(frame.Size); err != nil {
+			return nil, err
+		}
+	} else {
+		payload, err := tr.read(frame.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		tr.hashFrame(header, payload)
+
+		if err := tr.decodeFrame(frame, payload); err != nil {
+			if tr.o.Mode != ScanLenient || err == ErrDecompressedSizeLimit {
+				return nil, tr.frameError(frame.Offset-headerSize, frame.ID, err)
+			}
+
+			// The frame is skipped, but the frames that follow
+			// it can still be read.
+			tr.warn(frame.Offset-headerSize, frame.ID, err)
+			return nil, nil
+		}
+	}
+
+	if frame.ID == FrameSEEK {
+		if n, err := frame.SeekOffset(); err == nil {
+			tr.seek, tr.hasSeek = n, true
+		}
+	}
+
+	return frame, nil
+}
+
+// landsOnFrame reports whether the frame data that
+// begins at pos would, if it were size bytes long, be
+// followed by another frame, padding or the end of the
+// tag data.
+func (tr *tagReader) landsOnFrame(size uint32) bool {
+	if size == syncsafeInvalid || tr.end-tr.pos < int64(size) {
+		return false
+	}
+
+	off := tr.pos + int64(size)
+	if tr.end-off >= 10 {
+		header, err := tr.peek(off, 10)
+		if err != nil {
+			return false
+		}
+
+		switch frameID(header) {
+		case invalidFrameID:
+			return false
+		case 0:
+		default:
+			return true
+		}
+	}
+
+	// The remaining data must be padding.
+	for off < tr.end {
+		n := tr.end - off
+		if n > 4<<10 {
+			n = 4 << 10
+		}
+
+		data, err := tr.peek(off, n)
+		if err != nil {
+			return false
+		}
+
+		for _, v := range data {
+			if v != 0 {
+				return false
+			}
+		}
+
+		off += n
+	}
+
+	return true
+}
+
+// warn records a recoverable problem with the tag.
+func (tr *tagReader) warn(offset int64, id FrameID, err error) {
+	tr.tag.Warnings = append(tr.tag.Warnings, Warning{
+		Offset: tr.rawOffset(offset),
+		ID:     id,
+		Err:    err,
+	})
+}
+
+// rawOffset maps offset, a position within the tag data
+// as read, to its position in the reader before any
+// resynchronisation.
+func (tr *tagReader) rawOffset(offset int64) int64 {
+	if tr.removed == nil {
+		return offset
+	}
+
+	j := offset - tr.base
+	return offset + int64(sort.Search(len(tr.removed), func(i int) bool {
+		return tr.removed[i] > j
+	}))
+}
+
+// tagError returns err as a *TagError at offset.
+func (tr *tagReader) tagError(offset int64, err error) error {
+	return &TagError{
+		Offset:   tr.rawOffset(offset),
+		TagIndex: tr.index,
+		Err:      err,
+	}
+}
+
+// frameError returns err as a *FrameError for the frame
+// whose header is at offset.
+func (tr *tagReader) frameError(offset int64, id FrameID, err error) error {
+	return &FrameError{
+		Offset:   tr.rawOffset(offset),
+		TagIndex: tr.index,
+		ID:       id,
+		Err:      err,
+	}
+}
+
+// abandon returns err as a *FrameError, unless in lenient
+// mode where it records err as a warning at offset and
+// skips the rest of the frames. The frames already read
+// are kept.
+func (tr *tagReader) abandon(offset int64, id FrameID, err error) error {
+	if tr.o.Mode != ScanLenient {
+		return tr.frameError(offset, id, err)
+	}
+
+	tr.warn(offset, id, err)
+	return tr.skipFrames()
+}
+
+// abandonTag is like abandon, but for problems that are
+// not specific to a frame.
+func (tr *tagReader) abandonTag(offset int64, err error) error {
+	if tr.o.Mode != ScanLenient {
+		return tr.tagError(offset, err)
+	}
+
+	tr.warn(offset, 0, err)
+	return tr.skipFrames()
+}
+
+// skipFrames skips the rest of the frames and reads the
+// padding and footer as finish does.
+func (tr *tagReader) skipFrames() error {
+	// The CRC cannot be verified without the remaining
+	// frames.
+	tr.crc = nil
+
+	if err := tr.skip(tr.end - tr.pos); err != nil {
+		return err
+	}
+
+	return tr.finish(nil)
+}
+
+// truncated reports whether err is because the tag is
+// truncated and, in lenient mode, records it as a
+// warning. The frames already read are kept.
+func (tr *tagReader) truncated(err error) bool {
+	var te *TagError
+	if tr.o.Mode != ScanLenient || !errors.As(err, &te) || te.Err != ErrTruncatedTag {
+		return false
+	}
+
+	tr.warn(tr.tag.Offset, 0, ErrTruncatedTag)
+	tr.done = true
+	return true
+}
+
+// decodeFrame reverses the frame-level encodings of
+// payload, the frame data as read from the tag.
+func (tr *tagReader) decodeFrame(frame *Frame, payload []byte) error {
+	version, flags := tr.tag.Version, tr.tag.Flags
+
+	frame.Data = payload
+	if version == Version24 && (flags&TagFlagUnsynchronisation == TagFlagUnsynchronisation ||
+		frame.Flags&FrameFlagV24Unsynchronisation != 0) {
+		frame.Data = resynchronise(payload)
+
+		// Clear the frame level unsynchronisation flag
+		frame.Flags &^= FrameFlagV24Unsynchronisation
+	}
+
+	if err := frame.decode(tr.o.MaxDecompressedSize); err != nil {
+		return err
+	}
+
+	if version == Version22 && frame.ID == FrameAPIC {
+		frame.Data = picToAPIC(frame.Data)
+	}
+
+	if len(tr.o.Decrypters) == 0 {
+		return nil
+	}
+
+	// Frames can only be decrypted here if the ENCR frame
+	// precedes them.
+	if frame.ID == FrameENCR {
+		reg, err := frame.EncryptionRegistration()
+		if err != nil {
+			// The frame is kept, but frames encrypted with the
+			// method it registers are left encrypted.
+			if tr.o.Mode == ScanLenient {
+				tr.warn(frame.Offset-10, frame.ID, err)
+			}
+
+			return nil
+		}
+
+		if tr.regs == nil {
+			tr.regs = make(map[byte]*EncryptionRegistration)
+		}
+
+		tr.regs[reg.Symbol] = reg
+		return nil
+	}
+
+	return tr.o.decryptFrame(frame, tr.regs)
+}
+
+// finish reads and validates the padding and footer of
+// the tag, where padding holds any padding that has
+// already been read. It returns io.EOF if the tag is
+// valid.
+func (tr *tagReader) finish(padding []byte) error {
+	tr.done = true
+
+	n := int64(len(padding)) + tr.end - tr.pos
+	if tr.tag.HasFooter() && n != 0 {
+		offset := tr.pos - int64(len(padding))
+		if tr.o.Mode != ScanLenient {
+			return tr.tagError(offset, ErrPaddingWithFooter)
+		}
+
+		tr.warn(offset, 0, ErrPaddingWithFooter)
+	}
+
+	invalid := false
+	for {
+		for i, v := range padding {
+			if v == 0 || invalid {
+				continue
+			}
+
+			offset := tr.pos - int64(len(padding)-i)
+			if tr.o.Mode != ScanLenient {
+				return tr.tagError(offset, ErrInvalidPadding)
+			}
+
+			tr.warn(offset, 0, ErrInvalidPadding)
+			invalid = true
+		}
+
+		if tr.crc != nil && tr.tag.Version == Version24 {
+			// Quoting from §3.2 of id3v2.4.0-structure.txt:
+			//   The CRC is calculated on all the data between the
+			//   header and footer as indicated by the header's tag
+			//   length field, minus the extended header.
+			tr.crc.Write(padding)
+		}
+
+		if tr.pos == tr.end {
+			break
+		}
+
+		chunk := tr.end - tr.pos
+		if chunk > 4<<10 {
+			chunk = 4 << 10
+		}
+
+		var err error
+		if padding, err = tr.read(chunk); err != nil {
+			return err
+		}
+	}
+
+	tr.tag.Padding = n
+
+	if tr.tag.HasFooter() {
+		footer, err := tr.read(10)
+		if err != nil {
+			return err
+		}
+
+		if string(footer[:3]) != "3DI" ||
+			!bytes.Equal(tr.header[3:], footer[3:]) {
+			if tr.o.Mode != ScanLenient {
+				return tr.tagError(tr.pos-10, ErrInvalidFooter)
+			}
+
+			tr.warn(tr.pos-10, 0, ErrInvalidFooter)
+		}
+	}
+
+	if tr.crc != nil && tr.crc.Sum32() != tr.tag.ExtendedHeader.CRC {
+		switch tr.o.Mode {
+		case ScanStrict:
+			return tr.tagError(tr.tag.Offset, ErrCRCMismatch)
+		case ScanLenient:
+			tr.warn(tr.tag.Offset, 0, ErrCRCMismatch)
+		}
+
+		tr.tag.CRCMismatch = true
+	}
+
+	return io.EOF
+}
+
+// nextTag returns the offset of the tag that the SEEK
+// frame of the tag points to, or -1 if it does not have
+// a SEEK frame.
+func (tr *tagReader) nextTag() int64 {
+	if !tr.hasSeek {
+		return -1
+	}
+
+	return tr.tag.Offset + tr.tag.Size + int64(tr.seek)
+}
+
+// readTag reads every frame of the tag, as described by
+// newTagReader. It also returns the offset of the next
+// tag given by a SEEK frame, or -1.
+func (o *ScanOptions) readTag(header []byte, r io.Reader, index int, offset, size int64, lazy io.ReaderAt) (*Tag, int64, error) {
+	tr, err := o.newTagReader(header, r, index, offset, size, lazy)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for {
+		frame, err := tr.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, -1, err
+		}
+
+		tr.tag.Frames = append(tr.tag.Frames, frame)
+	}
+
+	// Decrypt any frames that precede their ENCR frame.
+	err = o.decrypt(tr.tag.Frames, func(f *Frame, err error) error {
+		if o.Mode != ScanLenient || err == ErrDecompressedSizeLimit {
+			return tr.frameError(f.Offset-10, f.ID, err)
+		}
+
+		tr.warn(f.Offset-10, f.ID, err)
+		return nil
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return tr.tag, tr.nextTag(), nil
+}
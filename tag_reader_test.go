@@ -0,0 +1,348 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanV23UnsynchronisedFrameSize(t *testing.T) {
+	// A 255 byte frame has a size of 00 00 00 FF, which is
+	// followed by a zero flag byte and so is unsynchronised
+	// along with the rest of the tag.
+	title := strings.Repeat("a", 254)
+
+	var body []byte
+	body = append(body, rawFrame("TIT2", 0, textData(title))...)
+	body = append(body, rawFrame("TPE1", 0, textData("\xffartist"))...)
+
+	raw := unsynchronise(body)
+	if bytes.Equal(raw, body) {
+		t.Fatal("unsynchronise did not modify the tag")
+	}
+
+	data := rawTag(Version23, TagFlagUnsynchronisation, raw)
+
+	tags, err := ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 1 || len(tags[0].Frames) != 2 {
+		t.Fatalf("got %d tags, want 1 tag with 2 frames", len(tags))
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, title)
+	checkText(t, tags[0].Frames, FrameTPE1, "ÿartist")
+
+	// The same frames are read by a Reader.
+	r := NewReader(bytes.NewReader(data))
+	for _, want := range tags[0].Frames {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+
+		if got.ID != want.ID || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestScanV24UnsynchronisedFrame(t *testing.T) {
+	frames := Frames{{
+		ID:      FrameAPIC,
+		Version: Version24,
+		Flags:   FrameFlagV24Unsynchronisation,
+		Data:    []byte("\x00image/jpeg\x00\x03\x00\xff\xd8\xff\xe0\xff\x00"),
+	}}
+
+	tags, err := ScanTags(bytes.NewReader(encodeTag(t, frames, nil)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	f := tags[0].Frames[0]
+	if f.Flags != 0 || !bytes.Equal(f.Data, frames[0].Data) {
+		t.Errorf("got %v, want %v", f, frames[0])
+	}
+}
+
+func TestScanV24NonSyncsafeFrameSize(t *testing.T) {
+	for _, size := range []int{200, 256} {
+		// Written as a plain 32-bit integer, 200 is not a
+		// valid syncsafe integer and 256 reads as 128.
+		var body []byte
+		body = append(body, rawFrame("TIT2", 0, textData(strings.Repeat("a", size-1)))...)
+		body = append(body, rawFrame("TPE1", 0, textData("artist"))...)
+		body = append(body, make([]byte, 16)...)
+		data := rawTag(Version24, 0, body)
+
+		if _, err := ScanTags(bytes.NewReader(data)); err == nil {
+			t.Errorf("%d: ScanTags succeeded in default mode", size)
+		}
+
+		o := &ScanOptions{Mode: ScanLenient}
+		tags, err := o.ScanTags(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%d: ScanTags failed: %v", size, err)
+		}
+
+		tag := tags[0]
+		if len(tag.Frames) != 2 {
+			t.Fatalf("%d: got %d frames, want 2", size, len(tag.Frames))
+		}
+
+		checkText(t, tag.Frames, FrameTIT2, strings.Repeat("a", size-1))
+		checkText(t, tag.Frames, FrameTPE1, "artist")
+
+		if tag.Padding != 16 {
+			t.Errorf("%d: got padding %d, want 16", size, tag.Padding)
+		}
+
+		if len(tag.Warnings) != 1 || tag.Warnings[0].Err != ErrFrameSizeNotSyncsafe ||
+			tag.Warnings[0].ID != FrameTIT2 || tag.Warnings[0].Offset != 10 {
+			t.Errorf("%d: got warnings %v", size, tag.Warnings)
+		}
+	}
+}
+
+func TestScanV24SyncsafeFrameSizeLenient(t *testing.T) {
+	// Correctly encoded frames must not be reinterpreted.
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData(strings.Repeat("a", 255))},
+		{ID: FrameTPE1, Version: Version24, Data: textData("artist")},
+	}, &EncodeOptions{Padding: 16})
+
+	tags, err := (&ScanOptions{Mode: ScanLenient}).ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags[0].Frames) != 2 || len(tags[0].Warnings) != 0 {
+		t.Errorf("got %d frames and warnings %v", len(tags[0].Frames), tags[0].Warnings)
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	for _, tc := range []struct {
+		w    Warning
+		want string
+	}{
+		{Warning{10, FrameTIT2, ErrFrameSizeNotSyncsafe},
+			"id3: frame size is not syncsafe in TIT2 frame at offset 10"},
+		{Warning{20, 0, ErrFrameSizeNotSyncsafe},
+			"id3: frame size is not syncsafe at offset 20"},
+	} {
+		if got := tc.w.String(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestScanLenient(t *testing.T) {
+	good := rawTag(Version23, 0, rawFrame("TIT2", 0, textData("title")))
+
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		frames int
+		err    error
+	}{
+		{"invalid padding", append(rawFrame("TPE1", 0, textData("artist")), 0, 0, 1, 0),
+			1, ErrInvalidPadding},
+		{"invalid frame id", append(rawFrame("TPE1", 0, textData("artist")),
+			"TP!1\x00\x00\x00\x01\x00\x00x"...), 1, ErrInvalidFrameID},
+		{"invalid frame size", append(rawFrame("TPE1", 0, textData("artist")),
+			"TALB\x00\x00\x01\x00\x00\x00x"...), 1, ErrFrameTooLarge},
+		{"invalid compression", append(rawFrame("TPE1", 0, textData("artist")),
+			append(rawFrame("TALB", FrameFlagV23Compression, []byte("\x00\x00\x00\x05bogus")),
+				rawFrame("TCON", 0, textData("genre"))...)...), 2, ErrInvalidCompression},
+	} {
+		data := append(append([]byte(nil), good...), rawTag(Version23, 0, tc.data)...)
+
+		_, err := ScanTags(bytes.NewReader(data))
+		var fe *FrameError
+		var te *TagError
+		if !errors.Is(err, tc.err) || !errors.As(err, &fe) && !errors.As(err, &te) {
+			t.Errorf("%s: got %v in default mode, want %v", tc.name, err, tc.err)
+		}
+
+		o := &ScanOptions{Mode: ScanLenient}
+		tags, err := o.ScanTags(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: ScanTags failed: %v", tc.name, err)
+		}
+
+		if len(tags) != 2 {
+			t.Fatalf("%s: got %d tags, want 2", tc.name, len(tags))
+		}
+
+		checkText(t, tags[0].Frames, FrameTIT2, "title")
+		checkText(t, tags[1].Frames, FrameTPE1, "artist")
+
+		if len(tags[1].Frames) != tc.frames {
+			t.Errorf("%s: got %d frames, want %d", tc.name, len(tags[1].Frames), tc.frames)
+		}
+
+		if w := tags[1].Warnings; len(w) != 1 || !errors.Is(w[0].Err, tc.err) {
+			t.Errorf("%s: got warnings %v", tc.name, w)
+		}
+	}
+}
+
+func TestScanLenientFooter(t *testing.T) {
+	data := encodeTag(t, Frames{
+		{ID: FrameTIT2, Version: Version24, Data: textData("title")},
+	}, &EncodeOptions{Footer: true})
+
+	// Corrupt the footer identifier.
+	data[len(data)-10] = 'X'
+
+	if _, err := ScanTags(bytes.NewReader(data)); err == nil {
+		t.Error("ScanTags succeeded in default mode")
+	}
+
+	tags, err := (&ScanOptions{Mode: ScanLenient}).ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, "title")
+
+	if w := tags[0].Warnings; len(w) != 1 || w[0].Err != ErrInvalidFooter ||
+		w[0].Offset != int64(len(data)-10) {
+		t.Errorf("got warnings %v", w)
+	}
+}
+
+func TestScanLenientCRCMismatch(t *testing.T) {
+	eh := []byte{0, 0, 0, 10, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	data := rawTag(Version23, TagFlagExtendedHeader, append(eh, rawFrame("TIT2", 0, textData("title"))...))
+
+	tags, err := (&ScanOptions{Mode: ScanLenient}).ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if !tags[0].CRCMismatch {
+		t.Error("CRCMismatch not set")
+	}
+
+	if w := tags[0].Warnings; len(w) != 1 || w[0].Err != ErrCRCMismatch || w[0].Offset != 0 {
+		t.Errorf("got warnings %v", w)
+	}
+}
+
+func TestScanLenientTruncated(t *testing.T) {
+	good := rawTag(Version23, 0, rawFrame("TIT2", 0, textData("title")))
+	truncated := rawTag(Version23, 0, append(rawFrame("TPE1", 0, textData("artist")),
+		rawFrame("TALB", 0, textData("album"))...))
+	truncated = truncated[:len(truncated)-2]
+
+	o := &ScanOptions{Mode: ScanLenient}
+	tags, err := o.ScanTags(bytes.NewReader(append(append([]byte(nil), good...), truncated...)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 2 || len(tags[1].Frames) != 1 {
+		t.Fatalf("got %d tags, want 2 tags with the last having 1 frame", len(tags))
+	}
+
+	checkText(t, tags[1].Frames, FrameTPE1, "artist")
+
+	if w := tags[1].Warnings; len(w) != 1 || w[0].Err != ErrTruncatedTag {
+		t.Errorf("got warnings %v", w)
+	}
+
+	tags, err = o.ScanTagsAt(bytes.NewReader(truncated), int64(len(truncated)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	if len(tags) != 1 || len(tags[0].Frames) != 1 || len(tags[0].Warnings) != 1 {
+		t.Fatalf("got %d tags, want 1 tag with 1 frame", len(tags))
+	}
+
+	// Only the header of a tag at the end of the stream.
+	tags, err = o.ScanTags(bytes.NewReader(append(append([]byte(nil), good...), truncated[:10]...)))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 2 || len(tags[1].Frames) != 0 {
+		t.Fatalf("got %d tags, want 2 tags with the last having no frames", len(tags))
+	}
+}
+
+func TestScanLenientTruncatedUnsynchronised(t *testing.T) {
+	body := unsynchronise(append(rawFrame("PRIV", 0, []byte("owner\x00\xff\xe0")),
+		rawFrame("TALB", 0, textData("album"))...))
+	data := rawTag(Version23, TagFlagUnsynchronisation, body)
+	data = data[:len(data)-2]
+
+	if _, err := ScanTags(bytes.NewReader(data)); !errors.Is(err, ErrTruncatedTag) {
+		t.Errorf("got %v in default mode, want ErrTruncatedTag", err)
+	}
+
+	o := &ScanOptions{Mode: ScanLenient}
+	tags, err := o.ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 1 || len(tags[0].Frames) != 1 {
+		t.Fatalf("got %d tags, want 1 tag with 1 frame", len(tags))
+	}
+
+	if f := tags[0].Frames[0]; f.ID != FramePRIV || string(f.Data) != "owner\x00\xff\xe0" {
+		t.Errorf("got frame %v", f)
+	}
+
+	if w := tags[0].Warnings; len(w) != 1 || w[0].Err != ErrTruncatedTag {
+		t.Errorf("got warnings %v", w)
+	}
+
+	tags, err = o.ScanTagsAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ScanTagsAt failed: %v", err)
+	}
+
+	if len(tags) != 1 || len(tags[0].Frames) != 1 || len(tags[0].Warnings) != 1 {
+		t.Fatalf("got %d tags, want 1 tag with 1 frame", len(tags))
+	}
+}
+
+func TestScanLenientExtendedHeader(t *testing.T) {
+	good := rawTag(Version23, 0, rawFrame("TIT2", 0, textData("title")))
+	bad := rawTag(Version24, TagFlagExtendedHeader, append([]byte{0, 0, 0, 6, 2, 0},
+		rawFrame("TPE1", 0, textData("artist"))...))
+	data := append(append([]byte(nil), good...), bad...)
+
+	_, err := ScanTags(bytes.NewReader(data))
+	var te *TagError
+	if !errors.As(err, &te) || te.Err != ErrInvalidExtendedHeader || te.TagIndex != 1 {
+		t.Errorf("got %v in default mode, want ErrInvalidExtendedHeader", err)
+	}
+
+	tags, err := (&ScanOptions{Mode: ScanLenient}).ScanTags(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ScanTags failed: %v", err)
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("got %d tags, want 2", len(tags))
+	}
+
+	checkText(t, tags[0].Frames, FrameTIT2, "title")
+
+	if w := tags[1].Warnings; len(w) != 1 || w[0].Err != ErrInvalidExtendedHeader {
+		t.Errorf("got warnings %v", w)
+	}
+}
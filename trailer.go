@@ -0,0 +1,225 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// TrailerKind is the kind of a block of metadata at the
+// end of a file.
+type TrailerKind int
+
+// These are the kinds of trailer found by FindTrailers.
+const (
+	TrailerID3v1 TrailerKind = iota + 1
+	TrailerAPE
+	TrailerLyrics3v1
+	TrailerLyrics3v2
+)
+
+func (k TrailerKind) String() string {
+	switch k {
+	case TrailerID3v1:
+		return "ID3v1"
+	case TrailerAPE:
+		return "APEv2"
+	case TrailerLyrics3v1:
+		return "Lyrics3v1"
+	case TrailerLyrics3v2:
+		return "Lyrics3v2"
+	default:
+		return fmt.Sprintf("TrailerKind(%d)", int(k))
+	}
+}
+
+// Trailer is a block of metadata at the end of a file.
+type Trailer struct {
+	Kind TrailerKind
+
+	// Offset is the position of the trailer relative to
+	// the start of the reader.
+	Offset int64
+
+	// Size is the total size of the trailer, including
+	// any header or footer.
+	Size int64
+}
+
+const (
+	apeFooterSize = 32
+	apeFlagHeader = 1 << 31
+
+	lyrics3v1MaxSize  = 5100 + 11 + 9
+	lyrics3v2Trailer  = 6 + 9
+	lyrics3BeginToken = "LYRICSBEGIN"
+)
+
+// FindTrailers returns the ID3v1, APEv2 and Lyrics3
+// blocks at the end of r, where size is the length of
+// r, in the order they appear.
+func FindTrailers(r io.ReaderAt, size int64) ([]Trailer, error) {
+	var trailers []Trailer
+	end := size
+
+	v1, err := ReadV1(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if v1 != nil {
+		trailers = append(trailers, Trailer{TrailerID3v1, v1.Offset, v1.Size})
+		end = v1.Offset
+	}
+
+	for {
+		t, err := findTrailer(r, end, v1 != nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if t == nil {
+			break
+		}
+
+		trailers = append(trailers, *t)
+		end = t.Offset
+	}
+
+	// The trailers were found from the end of r.
+	for i, j := 0, len(trailers)-1; i < j; i, j = i+1, j-1 {
+		trailers[i], trailers[j] = trailers[j], trailers[i]
+	}
+
+	return trailers, nil
+}
+
+// findTrailer returns the APEv2 or Lyrics3 block that
+// ends at end, or nil.
+func findTrailer(r io.ReaderAt, end int64, hasV1 bool) (*Trailer, error) {
+	if t, err := findAPE(r, end); t != nil || err != nil {
+		return t, err
+	}
+
+	if t, err := findLyrics3v2(r, end); t != nil || err != nil {
+		return t, err
+	}
+
+	if !hasV1 {
+		// Lyrics3v1 blocks are only valid when followed by an
+		// ID3v1 tag.
+		return nil, nil
+	}
+
+	return findLyrics3v1(r, end)
+}
+
+// readTail reads the n bytes that precede end. It
+// returns nil if there are fewer than n bytes.
+func readTail(r io.ReaderAt, end int64, n int) ([]byte, error) {
+	if end < int64(n) {
+		return nil, nil
+	}
+
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, end-int64(n)); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func findAPE(r io.ReaderAt, end int64) (*Trailer, error) {
+	footer, err := readTail(r, end, apeFooterSize)
+	if footer == nil || err != nil {
+		return nil, err
+	}
+
+	// The tag size includes the footer and items, but not
+	// the header.
+	size, flags, ok := parseAPEFooter(footer)
+	if !ok {
+		return nil, nil
+	}
+
+	if flags&apeFlagHeader != 0 {
+		size += apeFooterSize
+	}
+
+	if size > end {
+		return nil, nil
+	}
+
+	return &Trailer{TrailerAPE, end - size, size}, nil
+}
+
+// parseAPEFooter returns the tag size and flags from an
+// APEv2 header or footer.
+func parseAPEFooter(footer []byte) (size int64, flags uint32, ok bool) {
+	if string(footer[:8]) != "APETAGEX" {
+		return 0, 0, false
+	}
+
+	size = int64(binary.LittleEndian.Uint32(footer[12:]))
+	flags = binary.LittleEndian.Uint32(footer[20:])
+	return size, flags, size >= apeFooterSize
+}
+
+func findLyrics3v2(r io.ReaderAt, end int64) (*Trailer, error) {
+	tail, err := readTail(r, end, lyrics3v2Trailer)
+	if tail == nil || err != nil {
+		return nil, err
+	}
+
+	// The block ends with the six digit size of the block,
+	// excluding the size and the end token.
+	if string(tail[6:]) != "LYRICS200" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseUint(string(tail[:6]), 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+
+	size := int64(n) + lyrics3v2Trailer
+	begin, err := readTail(r, end-size+int64(len(lyrics3BeginToken)), len(lyrics3BeginToken))
+	if begin == nil || err != nil || string(begin) != lyrics3BeginToken {
+		return nil, err
+	}
+
+	return &Trailer{TrailerLyrics3v2, end - size, size}, nil
+}
+
+func findLyrics3v1(r io.ReaderAt, end int64) (*Trailer, error) {
+	tail, err := readTail(r, end, 9)
+	if tail == nil || err != nil || string(tail) != "LYRICSEND" {
+		return nil, err
+	}
+
+	// Lyrics3v1 blocks have no size, so the beginning of
+	// the block has to be searched for.
+	n := int64(lyrics3v1MaxSize)
+	if n > end {
+		n = end
+	}
+
+	block, err := readTail(r, end, int(n))
+	if err != nil {
+		return nil, err
+	}
+
+	i := bytes.LastIndex(block, []byte(lyrics3BeginToken))
+	if i == -1 {
+		return nil, nil
+	}
+
+	size := n - int64(i)
+	return &Trailer{TrailerLyrics3v1, end - size, size}, nil
+}
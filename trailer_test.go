@@ -0,0 +1,180 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a Modified
+// BSD License that can be found in the LICENSE file.
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// rawAPE returns an APEv2 tag holding the items, with a
+// header if header is true.
+func rawAPE(header bool, items ...*APEItem) []byte {
+	var body []byte
+	for _, item := range items {
+		var prefix [8]byte
+		binary.LittleEndian.PutUint32(prefix[:], uint32(len(item.Value)))
+		binary.LittleEndian.PutUint32(prefix[4:], item.Flags)
+		body = append(body, prefix[:]...)
+		body = append(body, item.Key...)
+		body = append(body, 0x00)
+		body = append(body, item.Value...)
+	}
+
+	footer := func(flags uint32) []byte {
+		data := make([]byte, apeFooterSize)
+		copy(data, "APETAGEX")
+		binary.LittleEndian.PutUint32(data[8:], 2000)
+		binary.LittleEndian.PutUint32(data[12:], uint32(len(body)+apeFooterSize))
+		binary.LittleEndian.PutUint32(data[16:], uint32(len(items)))
+		binary.LittleEndian.PutUint32(data[20:], flags)
+		return data
+	}
+
+	if !header {
+		return append(body, footer(0)...)
+	}
+
+	data := footer(apeFlagHeader | 1<<29)
+	data = append(data, body...)
+	return append(data, footer(apeFlagHeader)...)
+}
+
+// rawLyrics3v2 returns a Lyrics3v2 block with a single
+// lyrics field.
+func rawLyrics3v2(lyrics string) []byte {
+	block := lyrics3BeginToken + fmt.Sprintf("LYR%05d", len(lyrics)) + lyrics
+	return []byte(block + fmt.Sprintf("%06dLYRICS200", len(block)))
+}
+
+// rawLyrics3v1 returns a Lyrics3v1 block.
+func rawLyrics3v1(lyrics string) []byte {
+	return []byte(lyrics3BeginToken + lyrics + "LYRICSEND")
+}
+
+func TestFindTrailers(t *testing.T) {
+	v1 := rawV1("Title", "", "", "", "", 0, 0)
+	ape := rawAPE(true, &APEItem{Key: "Title", Value: []byte("Title")})
+	lyrics3v2 := rawLyrics3v2("lyrics")
+	lyrics3v1 := rawLyrics3v1("lyrics")
+
+	for _, tc := range []struct {
+		name  string
+		parts [][]byte
+		kinds []TrailerKind
+	}{
+		{"none", nil, nil},
+		{"ID3v1", [][]byte{v1}, []TrailerKind{TrailerID3v1}},
+		{"APEv2", [][]byte{rawAPE(false)}, []TrailerKind{TrailerAPE}},
+		{"APEv2 and ID3v1", [][]byte{ape, v1}, []TrailerKind{TrailerAPE, TrailerID3v1}},
+		{"all", [][]byte{ape, lyrics3v2, v1},
+			[]TrailerKind{TrailerAPE, TrailerLyrics3v2, TrailerID3v1}},
+		{"Lyrics3v1", [][]byte{lyrics3v1, v1}, []TrailerKind{TrailerLyrics3v1, TrailerID3v1}},
+		// Lyrics3v1 blocks must be followed by an ID3v1 tag.
+		{"Lyrics3v1 without ID3v1", [][]byte{lyrics3v1}, nil},
+	} {
+		data := []byte("audio")
+		var want []Trailer
+		for i, part := range tc.parts {
+			if i < len(tc.kinds) {
+				want = append(want, Trailer{tc.kinds[i], int64(len(data)), int64(len(part))})
+			}
+
+			data = append(data, part...)
+		}
+
+		got, err := FindTrailers(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: FindTrailers failed: %v", tc.name, err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestReadAPE(t *testing.T) {
+	items := []*APEItem{
+		{Key: "Title", Value: []byte("Title")},
+		{Key: "Artist", Value: []byte("One\x00Two")},
+		{Key: "Cover Art (Front)", Flags: uint32(APEItemBinary) << 1, Value: []byte("\x00\x01")},
+		{Key: "Album", Flags: 1, Value: []byte("Album")},
+	}
+
+	for _, header := range []bool{false, true} {
+		ape := rawAPE(header, items...)
+		data := append([]byte("audio"), ape...)
+		data = append(data, rawV1("", "", "", "", "", 0, 0)...)
+
+		tag, err := ReadAPE(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ReadAPE failed: %v", err)
+		}
+
+		if tag == nil || tag.Version != 2000 || tag.Offset != 5 || tag.Size != int64(len(ape)) {
+			t.Fatalf("got %+v", tag)
+		}
+
+		if !reflect.DeepEqual(tag.Items, items) {
+			t.Errorf("got items %v, want %v", tag.Items, items)
+		}
+
+		if text, err := tag.Lookup("artist").Text(); err != nil || text != "One\x00Two" {
+			t.Errorf("got artist %q, %v", text, err)
+		}
+
+		if cover := tag.Lookup("Cover Art (Front)"); cover.Type() != APEItemBinary {
+			t.Errorf("got type %d, want %d", cover.Type(), APEItemBinary)
+		} else if _, err := cover.Text(); err == nil {
+			t.Error("Text succeeded for binary item")
+		}
+
+		if !tag.Lookup("Album").ReadOnly() || tag.Lookup("Title").ReadOnly() {
+			t.Error("ReadOnly returned the wrong value")
+		}
+
+		if tag.Lookup("Genre") != nil {
+			t.Error("Lookup returned an item for a missing key")
+		}
+	}
+
+	data := []byte("audio")
+	if tag, err := ReadAPE(bytes.NewReader(data), int64(len(data))); tag != nil || err != nil {
+		t.Errorf("ReadAPE returned %+v, %v for data without tag", tag, err)
+	}
+}
+
+func TestStripFileTrailers(t *testing.T) {
+	frames := Frames{{ID: FrameTIT2, Version: Version24, Data: textData("title")}}
+
+	var trailers []byte
+	trailers = append(trailers, rawAPE(true, &APEItem{Key: "Title", Value: []byte("Title")})...)
+	trailers = append(trailers, rawV1("Title", "", "", "", "", 0, 0)...)
+
+	data := encodeTag(t, frames, nil)
+	data = append(data, "audio"...)
+	data = append(data, trailers...)
+
+	path, cleanup := writeTempFile(t, data)
+	defer cleanup()
+
+	if err := StripFile(path); err != nil {
+		t.Fatalf("StripFile failed: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := append([]byte("audio"), trailers...); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}